@@ -0,0 +1,70 @@
+// Package aggregate parses measurement files of the form "station;temperature"
+// and calculates the min, mean and max temperature of every station.
+package aggregate
+
+// Measurements holds the running statistics of a single station.
+// Temperatures are stored as integer tenths of a degree so that the sums are
+// exact and the results do not depend on the order chunks are merged in.
+type Measurements struct {
+	Min, Max, Sum int64
+	Count         int64
+}
+
+// MinTemperature returns the lowest temperature of the station.
+func (m *Measurements) MinTemperature() float64 {
+	return float64(m.Min) / 10
+}
+
+// MaxTemperature returns the highest temperature of the station.
+func (m *Measurements) MaxTemperature() float64 {
+	return float64(m.Max) / 10
+}
+
+// Mean returns the average temperature of the station.
+func (m *Measurements) Mean() float64 {
+	return float64(m.Sum) / float64(m.Count) / 10
+}
+
+// Merge drains resultsCh and combines the partial results it receives, for
+// example those of ProcessReader on several sources, into a single map keyed
+// by station name.
+// The Measurements received from the channel are reused in the returned map.
+func Merge(resultsCh <-chan map[string]*Measurements) map[string]*Measurements {
+	finalMap := make(map[string]*Measurements, 200)
+	for result := range resultsCh {
+		mergeInto(finalMap, result)
+	}
+
+	return finalMap
+}
+
+// mergeInto combines result into finalMap, reusing the Measurements of
+// result.
+func mergeInto(finalMap, result map[string]*Measurements) {
+	for station, newMeasurement := range result {
+		existentMeasurement, ok := finalMap[station]
+		if !ok {
+			finalMap[station] = newMeasurement
+			continue
+		}
+		existentMeasurement.Count += newMeasurement.Count
+		existentMeasurement.Sum += newMeasurement.Sum
+		if newMeasurement.Min < existentMeasurement.Min {
+			existentMeasurement.Min = newMeasurement.Min
+		}
+		if newMeasurement.Max > existentMeasurement.Max {
+			existentMeasurement.Max = newMeasurement.Max
+		}
+	}
+}
+
+// cloneResults returns a copy of results that does not share its
+// Measurements.
+func cloneResults(results map[string]*Measurements) map[string]*Measurements {
+	clone := make(map[string]*Measurements, len(results))
+	for station, measurements := range results {
+		copied := *measurements
+		clone[station] = &copied
+	}
+	return clone
+}
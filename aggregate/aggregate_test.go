@@ -0,0 +1,49 @@
+package aggregate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeInto(t *testing.T) {
+	results := []map[string]*Measurements{
+		{
+			"Abha":  {Min: -10, Max: 30, Sum: 20, Count: 2},
+			"Accra": {Min: 5, Max: 5, Sum: 5, Count: 1},
+		},
+		{
+			"Abha": {Min: -20, Max: 10, Sum: -10, Count: 2},
+		},
+		{
+			"Abha":  {Min: 0, Max: 40, Sum: 40, Count: 1},
+			"Aden":  {Min: 291, Max: 291, Sum: 291, Count: 1},
+			"Accra": {Min: -5, Max: 0, Sum: -5, Count: 2},
+		},
+	}
+	got := make(map[string]*Measurements)
+	for _, result := range results {
+		mergeInto(got, result)
+	}
+
+	expected := map[string]*Measurements{
+		"Abha":  {Min: -20, Max: 40, Sum: 50, Count: 5},
+		"Accra": {Min: -5, Max: 5, Sum: 0, Count: 3},
+		"Aden":  {Min: 291, Max: 291, Sum: 291, Count: 1},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("mergeInto() = %v, expected %v", got, expected)
+	}
+}
+
+func TestMeasurements(t *testing.T) {
+	m := &Measurements{Min: -15, Max: 31, Sum: 37, Count: 3}
+	if got := m.MinTemperature(); got != -1.5 {
+		t.Errorf("MinTemperature() = %v", got)
+	}
+	if got := m.MaxTemperature(); got != 3.1 {
+		t.Errorf("MaxTemperature() = %v", got)
+	}
+	if got := m.Mean(); got != 37.0/3/10 {
+		t.Errorf("Mean() = %v", got)
+	}
+}
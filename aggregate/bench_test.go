@@ -0,0 +1,82 @@
+package aggregate_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"1brc/aggregate"
+	"1brc/generate"
+)
+
+const benchRows = 1000000
+
+func BenchmarkProcessFile(b *testing.B) {
+	path := filepath.Join(b.TempDir(), "measurements.txt")
+	if _, err := generate.MeasurementFile(path, benchRows, generate.Options{}); err != nil {
+		b.Fatal(err)
+	}
+
+	workerCounts := []int{1}
+	if n := runtime.GOMAXPROCS(0); n > 1 {
+		workerCounts = append(workerCounts, n)
+	}
+
+	for _, mode := range []string{"reader", "mmap"} {
+		for _, table := range []string{"map", "open"} {
+			for _, workers := range workerCounts {
+				chunkSizes := []int{1, 8, 30}
+				if mode == "mmap" {
+					// the whole file is mapped
+					chunkSizes = []int{0}
+				}
+				for _, chunkSize := range chunkSizes {
+					name := fmt.Sprintf("%s/%s/workers-%d", mode, table, workers)
+					if chunkSize > 0 {
+						name += fmt.Sprintf("/chunk-%dMB", chunkSize)
+					}
+					opts := aggregate.Options{
+						Workers:   workers,
+						ChunkSize: chunkSize * 1024 * 1024,
+						NewTable:  aggregate.Tables[table],
+					}
+					b.Run(name, func(b *testing.B) {
+						benchmarkProcessFile(b, path, mode == "mmap", opts)
+					})
+				}
+			}
+		}
+	}
+}
+
+func benchmarkProcessFile(b *testing.B, path string, mmap bool, opts aggregate.Options) {
+	file, err := os.Open(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer file.Close()
+	stats, err := file.Stat()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	processFile := aggregate.ProcessFile
+	if mmap {
+		processFile = aggregate.ProcessFileMmap
+	}
+
+	b.SetBytes(stats.Size())
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := file.Seek(0, 0); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := processFile(file, stats.Size(), opts); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(benchRows)*float64(b.N)/b.Elapsed().Seconds(), "rows/s")
+}
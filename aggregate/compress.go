@@ -0,0 +1,122 @@
+package aggregate
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Compression is the compression of the input.
+type Compression int
+
+const (
+	// CompressionAuto detects the compression from the magic bytes at the
+	// start of the input, uncompressed input has none.
+	CompressionAuto Compression = iota
+	// CompressionNone reads the input as it is.
+	CompressionNone
+	// Gzip decompresses single or multi-member gzip input. The members of
+	// a regular file are decompressed concurrently.
+	Gzip
+	// Bzip2 decompresses bzip2 input.
+	Bzip2
+	// Zlib decompresses zlib input.
+	Zlib
+)
+
+var compressionNames = map[Compression]string{
+	CompressionAuto: "auto",
+	CompressionNone: "none",
+	Gzip:            "gzip",
+	Bzip2:           "bzip2",
+	Zlib:            "zlib",
+}
+
+// CompressionNames returns the names of the compressions accepted by
+// ParseCompression.
+func CompressionNames() []string {
+	return []string{compressionNames[CompressionAuto], compressionNames[CompressionNone], compressionNames[Gzip], compressionNames[Bzip2], compressionNames[Zlib]}
+}
+
+// ParseCompression returns the Compression called name.
+func ParseCompression(name string) (Compression, error) {
+	for compression, compressionName := range compressionNames {
+		if compressionName == name {
+			return compression, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown compression %q, expected one of %v", name, CompressionNames())
+}
+
+func (c Compression) String() string {
+	if name, ok := compressionNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("Compression(%d)", int(c))
+}
+
+// headerSize is the number of bytes DetectCompression looks at.
+const headerSize = 4
+
+// DetectCompression returns the compression whose magic bytes start header,
+// CompressionNone if there are none.
+// zlib has no real magic bytes, only the headers written by the usual
+// compression levels are recognized so that a station name starting with 'x'
+// is not taken for one.
+func DetectCompression(header []byte) Compression {
+	switch {
+	case bytes.HasPrefix(header, []byte{0x1f, 0x8b}):
+		return Gzip
+	case len(header) >= 4 && bytes.HasPrefix(header, []byte("BZh")) && header[3] >= '1' && header[3] <= '9':
+		return Bzip2
+	case len(header) >= 2 && header[0] == 0x78 && bytes.IndexByte([]byte{0x01, 0x5e, 0x9c, 0xda}, header[1]) >= 0:
+		return Zlib
+	}
+	return CompressionNone
+}
+
+// decompress returns a reader of the decompressed content of r.
+func decompress(r io.Reader, compression Compression) (io.Reader, error) {
+	if compression == CompressionAuto {
+		header := make([]byte, headerSize)
+		n, err := io.ReadFull(r, header)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return nil, err
+		}
+		header = header[:n]
+		compression = DetectCompression(header)
+		r = io.MultiReader(bytes.NewReader(header), r)
+	}
+
+	var err error
+	switch compression {
+	case Gzip:
+		r, err = gzip.NewReader(r)
+	case Bzip2:
+		r = bzip2.NewReader(r)
+	case Zlib:
+		r, err = zlib.NewReader(r)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %v input: %w", compression, err)
+	}
+	return r, nil
+}
+
+// detectFile returns the compression of the regular file at offset, without
+// moving the offset of the file.
+func detectFile(file *os.File, offset int64, compression Compression) (Compression, error) {
+	if compression != CompressionAuto {
+		return compression, nil
+	}
+	header := make([]byte, headerSize)
+	n, err := file.ReadAt(header, offset)
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	return DetectCompression(header[:n]), nil
+}
@@ -0,0 +1,152 @@
+package aggregate
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDetectCompression(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected Compression
+	}{
+		{"\x1f\x8b\x08\x00", Gzip},
+		{"BZh9", Bzip2},
+		{"BZh;", CompressionNone},
+		{"x\x9c", Zlib},
+		{"x;1.", CompressionNone},
+		{"Abha", CompressionNone},
+		{"", CompressionNone},
+	}
+	for _, test := range tests {
+		if got := DetectCompression([]byte(test.header)); got != test.expected {
+			t.Errorf("DetectCompression(%q) = %v, expected %v", test.header, got, test.expected)
+		}
+	}
+}
+
+// compressedInput returns measurements whose stored gzip members contain the
+// gzip magic bytes, so that the parallel reader has candidates to discard.
+func compressedInput() []byte {
+	var input bytes.Buffer
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&input, "Station%d;%d.%d\n", i%13, i%90-45, i%10)
+		if i%100 == 0 {
+			input.WriteString("\x1f\x8b\x08\x00;1.0\n")
+		}
+	}
+	return input.Bytes()
+}
+
+// gzipMembers compresses every part of data of size bytes into its own
+// member, alternating stored and compressed members.
+func gzipMembers(t *testing.T, data []byte, size int) []byte {
+	var compressed bytes.Buffer
+	for i := 0; len(data) > 0; i++ {
+		n := size
+		if n > len(data) {
+			n = len(data)
+		}
+		level := gzip.BestSpeed
+		if i%2 == 0 {
+			level = gzip.NoCompression
+		}
+		writer, err := gzip.NewWriterLevel(&compressed, level)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writer.Write(data[:n])
+		writer.Close()
+		data = data[n:]
+	}
+	return compressed.Bytes()
+}
+
+func TestProcessCompressed(t *testing.T) {
+	input := compressedInput()
+	expected, err := ProcessReader(bytes.NewReader(input), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var zlibInput bytes.Buffer
+	writer := zlib.NewWriter(&zlibInput)
+	writer.Write(input)
+	writer.Close()
+
+	inputs := map[string][]byte{
+		"gzip":         gzipMembers(t, input, len(input)),
+		"gzip-members": gzipMembers(t, input, 1000),
+		"zlib":         zlibInput.Bytes(),
+	}
+	for name, data := range inputs {
+		results, err := ProcessReader(bytes.NewReader(data), Options{Workers: 2, ChunkSize: 512})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(results, expected) {
+			t.Errorf("%s: ProcessReader got %v, expected %v", name, results, expected)
+		}
+
+		file := writeTemp(t, data)
+		for _, processFile := range []func(*os.File, int64, Options) (map[string]*Measurements, error){ProcessFile, ProcessFileMmap} {
+			// the size given by the caller is ignored
+			for _, size := range []int64{int64(len(data)), 0, 7} {
+				if _, err := file.Seek(0, 0); err != nil {
+					t.Fatal(err)
+				}
+				results, err := processFile(file, size, Options{Workers: 2, ChunkSize: 512})
+				if err != nil {
+					t.Fatalf("%s with size %d: %v", name, size, err)
+				}
+				if !reflect.DeepEqual(results, expected) {
+					t.Errorf("%s with size %d: got %v, expected %v", name, size, results, expected)
+				}
+			}
+		}
+	}
+}
+
+func TestProcessBzip2(t *testing.T) {
+	input, err := os.ReadFile(filepath.Join("testdata", "rounding.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := ProcessReader(bytes.NewReader(input), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(filepath.Join("testdata", "rounding.txt.bz2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	results, err := ProcessFile(file, 0, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("got %v, expected %v", results, expected)
+	}
+}
+
+func TestParallelGzipErrors(t *testing.T) {
+	data := gzipMembers(t, compressedInput(), 1000)
+	inputs := map[string][]byte{
+		"truncated": data[:len(data)-3],
+		"garbage":   append(append([]byte{}, data...), "Abha;1.0\n"...),
+		"corrupt":   append(append([]byte{}, data[:len(data)/2]...), bytes.Repeat([]byte{0xff}, len(data)/2)...),
+	}
+	for name, input := range inputs {
+		file := writeTemp(t, input)
+		if _, err := ProcessFile(file, int64(len(input)), Options{Workers: 3, ChunkSize: 512}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package aggregate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ProcessFiles processes the files at paths like ProcessFile, one after the
+// other, with one shared pool of buffers and workers: the chunks of the
+// next file are read while the workers still process the last chunks of
+// the previous one. It returns the measurements of all the files merged,
+// and those of every file in opts.PerFile if it is not nil.
+// Lines never span files and the errors report the path of the file.
+func ProcessFiles(paths []string, opts Options) (map[string]*Measurements, error) {
+	opts = opts.withDefaults()
+	p := newProcessor(opts)
+	pool := newBufferPool(opts.PoolSize, opts.ChunkSize)
+
+	var readErr error
+	for _, path := range paths {
+		if p.failed.Load() {
+			break
+		}
+		p.startFile(path)
+		if readErr = p.readPath(path, pool); readErr != nil {
+			break
+		}
+	}
+	return p.finish(readErr)
+}
+
+func (p *processor) readPath(path string, pool *bufferPool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := p.readFile(file, pool); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// ExpandPaths replaces the glob patterns of patterns, as matched by
+// filepath.Match, with the files matching them in lexical order. Paths
+// without '*', '?' or '[' are kept as they are, so that a missing file is
+// reported when it is opened. A pattern matching no file is an error.
+func ExpandPaths(patterns []string) ([]string, error) {
+	var paths []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			if strings.ContainsAny(pattern, "*?[") {
+				return nil, fmt.Errorf("no file matches %q", pattern)
+			}
+			paths = append(paths, pattern)
+			continue
+		}
+		paths = append(paths, matches...)
+	}
+	return paths, nil
+}
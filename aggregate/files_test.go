@@ -0,0 +1,176 @@
+package aggregate
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes every content to its own file in a temporary directory
+// and returns their paths.
+func writeFiles(t *testing.T, contents ...[]byte) []string {
+	t.Helper()
+	dir := t.TempDir()
+	paths := make([]string, len(contents))
+	for i, content := range contents {
+		paths[i] = filepath.Join(dir, fmt.Sprintf("part-%02d.txt", i))
+		if err := os.WriteFile(paths[i], content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return paths
+}
+
+// writeTemp writes data to a temporary file and returns it opened.
+func writeTemp(t *testing.T, data []byte) *os.File {
+	t.Helper()
+	file, err := os.Open(writeFiles(t, data)[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { file.Close() })
+	return file
+}
+
+func TestProcessFiles(t *testing.T) {
+	var contents [][]byte
+	var all bytes.Buffer
+	for i := 0; i < 20; i++ {
+		var content bytes.Buffer
+		for j := 0; j < i*30; j++ {
+			fmt.Fprintf(&content, "Station%d;%d.%d\n", j%(i+1), j%80-40, j%10)
+		}
+		all.Write(content.Bytes())
+		contents = append(contents, content.Bytes())
+	}
+	// the last line of a file does not continue in the next one
+	contents = append(contents, []byte("Abha;1.0"), []byte("Aden;2.0\n"))
+	all.WriteString("Abha;1.0\nAden;2.0\n")
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Write([]byte("Zürich;-3.5\n"))
+	writer.Close()
+	contents = append(contents, compressed.Bytes())
+	all.WriteString("Zürich;-3.5\n")
+
+	expected, err := ProcessReader(bytes.NewReader(all.Bytes()), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := writeFiles(t, contents...)
+	for _, opts := range []Options{{Workers: 1, ChunkSize: 64}, {Workers: 3, ChunkSize: 100, Mode: ParseStrict}, {}} {
+		results, err := ProcessFiles(paths, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(results, expected) {
+			t.Errorf("%+v: got %v, expected %v", opts, results, expected)
+		}
+	}
+
+	perFile := make(map[string]map[string]*Measurements)
+	results, err := ProcessFiles(paths, Options{Workers: 2, ChunkSize: 64, PerFile: perFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("got %v, expected %v", results, expected)
+	}
+	if len(perFile) != len(paths) {
+		t.Fatalf("got the results of %d files, expected %d", len(perFile), len(paths))
+	}
+	for i, path := range paths {
+		fileExpected, err := ProcessReader(bytes.NewReader(contents[i]), Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(perFile[path], fileExpected) {
+			t.Errorf("%s: got %v, expected %v", path, perFile[path], fileExpected)
+		}
+	}
+}
+
+func TestPerFileSingleInput(t *testing.T) {
+	input := []byte("a;1.0\nb;2.0\na;3.0\n")
+	expected := map[string]*Measurements{
+		"a": {Min: 10, Max: 30, Sum: 40, Count: 2},
+		"b": {Min: 20, Max: 20, Sum: 20, Count: 1},
+	}
+	file := writeTemp(t, input)
+	processors := map[string]func(Options) (map[string]*Measurements, error){
+		"ProcessReader": func(opts Options) (map[string]*Measurements, error) {
+			return ProcessReader(bytes.NewReader(input), opts)
+		},
+		"ProcessFile": func(opts Options) (map[string]*Measurements, error) {
+			file.Seek(0, 0)
+			return ProcessFile(file, int64(len(input)), opts)
+		},
+		"ProcessFileMmap": func(opts Options) (map[string]*Measurements, error) {
+			return ProcessFileMmap(file, int64(len(input)), opts)
+		},
+	}
+	for name, process := range processors {
+		perFile := make(map[string]map[string]*Measurements)
+		results, err := process(Options{PerFile: perFile})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(results, expected) {
+			t.Errorf("%s: got %v, expected %v", name, results, expected)
+		}
+		if len(perFile) != 0 {
+			t.Errorf("%s: filled PerFile with %v", name, perFile)
+		}
+	}
+}
+
+func TestProcessFilesErrors(t *testing.T) {
+	paths := writeFiles(t, []byte("a;1.0\nb;2.0\n"), []byte("a;1.0\nb;2.0\nc 3.0\n"))
+	_, err := ProcessFiles(paths, Options{Mode: ParseStrict})
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected a ParseError, got %v", err)
+	}
+	if parseErr.Path != paths[1] || parseErr.Line != 3 || parseErr.Offset != 12 {
+		t.Errorf("got %v, expected line 3 (byte offset 12) of %s", err, paths[1])
+	}
+
+	var skipped Skipped
+	if _, err := ProcessFiles(paths, Options{Mode: ParseLenient, Skipped: &skipped, MaxSamples: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if len(skipped.Samples) != 1 || skipped.Samples[0].Path != paths[1] || skipped.Samples[0].Line != 3 {
+		t.Errorf("skipped samples = %v", skipped.Samples)
+	}
+
+	missing := filepath.Join(t.TempDir(), "missing.txt")
+	if _, err := ProcessFiles(append(paths, missing), Options{}); err == nil || !strings.Contains(err.Error(), missing) {
+		t.Errorf("expected an error about %s, got %v", missing, err)
+	}
+}
+
+func TestExpandPaths(t *testing.T) {
+	paths := writeFiles(t, []byte("a;1.0\n"), []byte("b;1.0\n"), []byte("c;1.0\n"))
+	dir := filepath.Dir(paths[0])
+	missing := filepath.Join(dir, "missing.txt")
+
+	expanded, err := ExpandPaths([]string{filepath.Join(dir, "part-0[12].txt"), paths[0], missing})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{paths[1], paths[2], paths[0], missing}
+	if !reflect.DeepEqual(expanded, expected) {
+		t.Errorf("got %v, expected %v", expanded, expected)
+	}
+
+	if _, err := ExpandPaths([]string{filepath.Join(dir, "*.csv")}); err == nil {
+		t.Error("a pattern matching no file should fail")
+	}
+}
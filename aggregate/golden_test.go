@@ -0,0 +1,98 @@
+package aggregate_test
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"1brc/aggregate"
+	"1brc/output"
+	"1brc/round"
+)
+
+var update = flag.Bool("update", false, "rewrite the expected outputs in testdata")
+
+// goldenOptions are the combinations every fixture is processed with.
+// The small chunk sizes split the input in the middle of lines.
+var goldenOptions = []struct {
+	name string
+	mmap bool
+	opts aggregate.Options
+}{
+	{"default", false, aggregate.Options{}},
+	{"chunk-128", false, aggregate.Options{Workers: 3, ChunkSize: 128}},
+	{"chunk-1000", false, aggregate.Options{Workers: 1, ChunkSize: 1000}},
+	{"open-table", false, aggregate.Options{ChunkSize: 256, NewTable: aggregate.NewOpenTable}},
+	{"strict", false, aggregate.Options{ChunkSize: 512, Mode: aggregate.ParseStrict}},
+	{"lenient", false, aggregate.Options{ChunkSize: 512, Mode: aggregate.ParseLenient, Skipped: &aggregate.Skipped{}}},
+	{"mmap-1", true, aggregate.Options{Workers: 1}},
+	{"mmap-7", true, aggregate.Options{Workers: 7}},
+	{"mmap-100", true, aggregate.Options{Workers: 100, NewTable: aggregate.NewOpenTable}},
+}
+
+func TestGolden(t *testing.T) {
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "*.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures) == 0 {
+		t.Fatal("no fixtures in testdata")
+	}
+
+	for _, fixture := range fixtures {
+		goldenPath := strings.TrimSuffix(fixture, ".txt") + ".out"
+		for _, o := range goldenOptions {
+			t.Run(fmt.Sprintf("%s/%s", filepath.Base(fixture), o.name), func(t *testing.T) {
+				got := processFixture(t, fixture, o.mmap, o.opts)
+				if *update {
+					if err := os.WriteFile(goldenPath, got, 0o644); err != nil {
+						t.Fatal(err)
+					}
+					return
+				}
+				expected, err := os.ReadFile(goldenPath)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, expected) {
+					t.Errorf("got\n%s\nexpected\n%s", got, expected)
+				}
+				if o.opts.Skipped != nil && o.opts.Skipped.Total() != 0 {
+					t.Errorf("skipped %d well formed lines", o.opts.Skipped.Total())
+				}
+			})
+		}
+	}
+}
+
+func processFixture(t *testing.T, path string, mmap bool, opts aggregate.Options) []byte {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	stats, err := file.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	processFile := aggregate.ProcessFile
+	if mmap {
+		processFile = aggregate.ProcessFileMmap
+	}
+	results, err := processFile(file, stats.Size(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := output.Official(&out, results, round.HalfUp); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
@@ -0,0 +1,252 @@
+package aggregate
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"sync"
+)
+
+const (
+	// gzipBlockSize is the size of the blocks of decompressed data handed
+	// from the decoders to the reader.
+	gzipBlockSize = 256 * 1024
+	// gzipBlocks is the number of blocks a decoder decompresses ahead of the
+	// reader.
+	gzipBlocks = 16
+	// gzipScanSize is the size of the blocks of the file scanned for headers.
+	gzipScanSize = 1024 * 1024
+)
+
+// gzipMagic starts every gzip member compressed with deflate.
+var gzipMagic = []byte{0x1f, 0x8b, 0x08}
+
+// parallelGzipReader decompresses the members of a gzip file concurrently.
+// The members are not indexed, so every occurrence of the gzip magic bytes
+// in the file is a candidate member that a decoder starts to decompress.
+// Reading follows the chain of members from the start of the file, every
+// member starting where the previous one ended: the candidates that are
+// not on the chain are magic bytes inside compressed data, their decoders
+// are cancelled and their output dropped.
+// At most workers decoders run at the same time, each with at most
+// gzipBlocks blocks of decompressed data ahead of the reader.
+type parallelGzipReader struct {
+	file  io.ReaderAt
+	start int64
+	end   int64
+
+	// members receives the candidates in the order of the file
+	members chan *gzipMember
+	// slots bounds the number of decoders
+	slots chan struct{}
+	done  chan struct{}
+	close sync.Once
+
+	current *gzipMember
+	block   []byte
+	// next is the offset of the next member of the chain
+	next int64
+	err  error
+}
+
+// gzipMember is the decompression of the candidate member at offset.
+type gzipMember struct {
+	offset int64
+	// blocks is closed at the end of the member, once end and err are set
+	blocks chan []byte
+	end    int64
+	err    error
+	cancel chan struct{}
+	// release frees the slot of the decoder, once its output was read or
+	// will never be
+	release func()
+}
+
+func newParallelGzipReader(file io.ReaderAt, start, end int64, workers int) *parallelGzipReader {
+	g := &parallelGzipReader{
+		file:    file,
+		start:   start,
+		end:     end,
+		members: make(chan *gzipMember, workers),
+		slots:   make(chan struct{}, workers),
+		done:    make(chan struct{}),
+		next:    start,
+	}
+	go g.scan()
+	return g
+}
+
+// scan finds the candidate members and starts their decoders in order.
+func (g *parallelGzipReader) scan() {
+	defer close(g.members)
+	// the blocks overlap so that magic bytes across two blocks are found
+	buffer := make([]byte, gzipScanSize+len(gzipMagic)-1)
+	for position := g.start; position < g.end; position += gzipScanSize {
+		size := int64(len(buffer))
+		if g.end-position < size {
+			size = g.end - position
+		}
+		n, err := g.file.ReadAt(buffer[:size], position)
+		if err != nil && err != io.EOF {
+			g.send(&gzipMember{offset: position, err: err})
+			return
+		}
+		data := buffer[:n]
+		for i := 0; ; i++ {
+			index := bytes.Index(data[i:], gzipMagic)
+			if index == -1 || i+index >= gzipScanSize {
+				break
+			}
+			i += index
+			select {
+			case g.slots <- struct{}{}:
+			case <-g.done:
+				return
+			}
+			var once sync.Once
+			member := &gzipMember{
+				offset: position + int64(i),
+				blocks: make(chan []byte, gzipBlocks),
+				cancel: make(chan struct{}),
+				release: func() {
+					once.Do(func() { <-g.slots })
+				},
+			}
+			go g.decode(member)
+			if !g.send(member) {
+				return
+			}
+		}
+	}
+}
+
+// send hands member to the reader, it returns false if the reader was
+// closed.
+func (g *parallelGzipReader) send(member *gzipMember) bool {
+	select {
+	case g.members <- member:
+		return true
+	case <-g.done:
+		return false
+	}
+}
+
+// decode decompresses the member until its end, an error or a cancellation.
+func (g *parallelGzipReader) decode(member *gzipMember) {
+	defer close(member.blocks)
+	counter := &countingReader{r: bufio.NewReader(io.NewSectionReader(g.file, member.offset, g.end-member.offset))}
+	reader, err := gzip.NewReader(counter)
+	if err != nil {
+		member.err = err
+		member.release()
+		return
+	}
+	reader.Multistream(false)
+	for {
+		block := make([]byte, gzipBlockSize)
+		n, err := readFull(reader, block)
+		if n > 0 {
+			select {
+			case member.blocks <- block[:n]:
+			case <-member.cancel:
+				member.release()
+				return
+			case <-g.done:
+				return
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// most likely a candidate that is not a member, its slot can
+			// already be used by the next one
+			member.err = err
+			member.release()
+			return
+		}
+	}
+	member.end = member.offset + counter.n
+}
+
+func (g *parallelGzipReader) Read(p []byte) (int, error) {
+	for len(g.block) == 0 {
+		if g.err != nil {
+			return 0, g.err
+		}
+		if g.current == nil {
+			g.current, g.err = g.nextMember()
+			continue
+		}
+		block, ok := <-g.current.blocks
+		if ok {
+			g.block = block
+			continue
+		}
+		// the member is over
+		if g.current.err != nil {
+			g.err = g.current.err
+			continue
+		}
+		g.next = g.current.end
+		g.current.release()
+		g.current = nil
+		if g.next == g.end {
+			g.err = io.EOF
+		}
+	}
+	n := copy(p, g.block)
+	g.block = g.block[n:]
+	return n, nil
+}
+
+// nextMember returns the candidate starting at the end of the previous
+// member and cancels the candidates before it.
+func (g *parallelGzipReader) nextMember() (*gzipMember, error) {
+	for member := range g.members {
+		if member.blocks == nil {
+			// scan failed
+			return nil, member.err
+		}
+		if member.offset == g.next {
+			return member, nil
+		}
+		close(member.cancel)
+		member.release()
+		if member.offset > g.next {
+			break
+		}
+	}
+	// the data after the last member is not a gzip member
+	return nil, gzip.ErrHeader
+}
+
+// Close stops the decoders.
+func (g *parallelGzipReader) Close() error {
+	g.close.Do(func() {
+		close(g.done)
+	})
+	return nil
+}
+
+// countingReader counts the bytes read from r. It is an io.ByteReader so
+// that gzip reads exactly the bytes of a member and no more.
+type countingReader struct {
+	r *bufio.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
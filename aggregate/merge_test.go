@@ -0,0 +1,30 @@
+package aggregate_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"1brc/aggregate"
+)
+
+func TestMergeSources(t *testing.T) {
+	sources := []string{"Abha;-1.0\nAccra;2.5\n", "Abha;3.0\n", "Aden;29.1\nAccra;-0.5\n"}
+	expected, err := aggregate.ProcessReader(strings.NewReader(strings.Join(sources, "")), aggregate.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resultsCh := make(chan map[string]*aggregate.Measurements, len(sources))
+	for _, source := range sources {
+		results, err := aggregate.ProcessReader(strings.NewReader(source), aggregate.Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resultsCh <- results
+	}
+	close(resultsCh)
+	if got := aggregate.Merge(resultsCh); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Merge() = %v, expected %v", got, expected)
+	}
+}
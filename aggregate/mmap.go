@@ -0,0 +1,91 @@
+package aggregate
+
+import (
+	"bytes"
+	"errors"
+	"os"
+)
+
+var errMmapUnsupported = errors.New("memory mapping is not supported on this platform")
+
+// ProcessFileMmap memory maps the measurements file, splits it into
+// opts.Workers byte ranges aligned to the end of a line and processes every range
+// directly from the mapping without copying it.
+// Inputs that cannot be mapped, like pipes, and compressed files are processed
+// with ProcessFile.
+// The size of the file is read from it, fileSize is only kept for
+// compatibility and ignored.
+func ProcessFileMmap(file *os.File, fileSize int64, opts Options) (map[string]*Measurements, error) {
+	opts = opts.withDefaults()
+	fileStats, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	fileSize = fileStats.Size()
+	if !fileStats.Mode().IsRegular() || int64(int(fileSize)) != fileSize {
+		return ProcessFile(file, fileSize, opts)
+	}
+	compression, err := detectFile(file, 0, opts.Compression)
+	if err != nil {
+		return nil, err
+	}
+	if compression != CompressionNone {
+		return ProcessFile(file, fileSize, opts)
+	}
+	if fileSize == 0 {
+		return make(map[string]*Measurements), nil
+	}
+
+	data, unmap, err := mmapFile(file, fileSize)
+	if err == errMmapUnsupported {
+		return ProcessFile(file, fileSize, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer unmap()
+
+	chunks := splitChunks(data, opts.Workers)
+
+	p := newProcessor(opts)
+	for _, chunk := range chunks {
+		if p.failed.Load() {
+			break
+		}
+		p.dispatch(chunk, func() {})
+	}
+
+	return p.wait()
+}
+
+// splitChunks splits data into at most n chunks of similar size.
+// Every chunk but the last one ends right after a '\n'.
+func splitChunks(data []byte, n int) [][]byte {
+	if n < 1 {
+		n = 1
+	}
+	chunkSize := len(data) / n
+	if chunkSize == 0 {
+		chunkSize = len(data)
+	}
+
+	chunks := make([][]byte, 0, n)
+	var start int
+	for start < len(data) {
+		end := start + chunkSize
+		if end >= len(data) || len(chunks) == n-1 {
+			chunks = append(chunks, data[start:])
+			break
+		}
+		newLine := bytes.IndexByte(data[end:], '\n')
+		if newLine == -1 {
+			chunks = append(chunks, data[start:])
+			break
+		}
+		end += newLine + 1
+		chunks = append(chunks, data[start:end])
+		start = end
+	}
+
+	return chunks
+}
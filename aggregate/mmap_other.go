@@ -0,0 +1,9 @@
+//go:build !unix
+
+package aggregate
+
+import "os"
+
+func mmapFile(file *os.File, size int64) ([]byte, func() error, error) {
+	return nil, nil, errMmapUnsupported
+}
@@ -0,0 +1,70 @@
+package aggregate
+
+import "runtime"
+
+// DefaultChunkSize is the default size in bytes of the chunks handed to the workers.
+const DefaultChunkSize = 30 * 1024 * 1024
+
+// Options configures how a measurements file is processed.
+// The zero value uses the defaults.
+type Options struct {
+	// Workers is the number of chunks processed concurrently. In mmap mode
+	// it is the number of byte ranges the file is split into.
+	// Defaults to GOMAXPROCS.
+	Workers int
+	// ChunkSize is the size in bytes of the buffers the file is read into.
+	// Lines must be shorter than ChunkSize. Defaults to DefaultChunkSize.
+	ChunkSize int
+	// PoolSize is the number of buffers the input is read into in reader
+	// mode, so the memory used for the chunks never exceeds
+	// PoolSize * ChunkSize. One buffer is the one being read, so at most
+	// PoolSize - 1 chunks are processed at the same time. It is at least 2.
+	// Defaults to Workers + 1.
+	PoolSize int
+	// NewTable creates the table every worker accumulates its chunk into.
+	// Defaults to NewMapTable.
+	NewTable func() Table
+	// Mode selects how malformed lines are handled. Defaults to ParseFast.
+	Mode ParseMode
+	// MaxNameLength is the maximum length in bytes of a station name in
+	// ParseStrict and ParseLenient modes, longer names are malformed.
+	// ParseFast accepts names of any length.
+	// Defaults to DefaultMaxNameLength.
+	MaxNameLength int
+	// Skipped, when not nil, receives the lines skipped in ParseLenient mode.
+	Skipped *Skipped
+	// MaxSamples is the number of skipped lines kept in Skipped.Samples.
+	MaxSamples int
+	// Compression is the compression of the input, the input is decompressed
+	// on the fly before it is split into chunks. The byte offsets of the
+	// errors are then offsets in the decompressed data.
+	// Defaults to CompressionAuto.
+	Compression Compression
+	// PerFile, when not nil, receives the results of every file of
+	// ProcessFiles keyed by path, in addition to the merged results. The
+	// other functions ignore it.
+	PerFile map[string]map[string]*Measurements
+}
+
+func (o Options) withDefaults() Options {
+	if o.Workers < 1 {
+		o.Workers = runtime.GOMAXPROCS(0)
+	}
+	if o.ChunkSize < 1 {
+		o.ChunkSize = DefaultChunkSize
+	}
+	if o.PoolSize < 1 {
+		o.PoolSize = o.Workers + 1
+	}
+	// the incomplete last line of a buffer is copied into the next one
+	if o.PoolSize < 2 {
+		o.PoolSize = 2
+	}
+	if o.MaxNameLength < 1 {
+		o.MaxNameLength = DefaultMaxNameLength
+	}
+	if o.NewTable == nil {
+		o.NewTable = NewMapTable
+	}
+	return o
+}
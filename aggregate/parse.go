@@ -0,0 +1,28 @@
+package aggregate
+
+// ParseTenths converts a temperature with exactly one decimal digit
+// (e.g. "-12.3") to tenths of a degree (-123) without going through a string.
+func ParseTenths(bytes []byte) int64 {
+	var startIndex int
+	if bytes[0] == '-' {
+		startIndex = 1
+	}
+
+	v := int64(bytes[len(bytes)-1] - '0') // single decimal digit
+	var place int64 = 10
+	for i := len(bytes) - 3; i >= startIndex; i-- { // integer part
+		v += int64(bytes[i]-'0') * place
+		place *= 10
+	}
+
+	if startIndex == 1 {
+		v *= -1
+	}
+	return v
+}
+
+// ParseTemperature converts a temperature with exactly one decimal digit
+// (e.g. "-12.3") to a float.
+func ParseTemperature(bytes []byte) float64 {
+	return float64(ParseTenths(bytes)) / 10
+}
@@ -0,0 +1,31 @@
+package aggregate
+
+import "testing"
+
+func TestParseTenths(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"0.0", 0},
+		{"-0.0", 0},
+		{"0.1", 1},
+		{"-0.1", -1},
+		{"1.5", 15},
+		{"-1.5", -15},
+		{"12.3", 123},
+		{"-12.3", -123},
+		{"99.9", 999},
+		{"-99.9", -999},
+	}
+
+	for _, test := range tests {
+		if got := ParseTenths([]byte(test.input)); got != test.expected {
+			t.Errorf("ParseTenths(%q) = %d, expected %d", test.input, got, test.expected)
+		}
+		expected := float64(test.expected) / 10
+		if got := ParseTemperature([]byte(test.input)); got != expected {
+			t.Errorf("ParseTemperature(%q) = %v, expected %v", test.input, got, expected)
+		}
+	}
+}
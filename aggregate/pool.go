@@ -0,0 +1,44 @@
+package aggregate
+
+// bufferPool hands out at most size buffers of chunkSize bytes.
+// Buffers are allocated lazily, get blocks until a buffer is put back once
+// all of them are in use.
+// get must be called from a single goroutine, put is safe for concurrent use.
+type bufferPool struct {
+	buffers   chan []byte
+	size      int
+	allocated int
+	chunkSize int
+}
+
+func newBufferPool(size, chunkSize int) *bufferPool {
+	return &bufferPool{
+		buffers:   make(chan []byte, size),
+		size:      size,
+		chunkSize: chunkSize,
+	}
+}
+
+func (p *bufferPool) get() []byte {
+	select {
+	case buffer := <-p.buffers:
+		return buffer
+	default:
+	}
+	if p.allocated < p.size {
+		p.allocated++
+		return make([]byte, p.chunkSize)
+	}
+	return <-p.buffers
+}
+
+func (p *bufferPool) put(buffer []byte) {
+	p.buffers <- buffer[:cap(buffer)]
+}
+
+// releaser returns a function putting buffer back into the pool.
+func (p *bufferPool) releaser(buffer []byte) func() {
+	return func() {
+		p.put(buffer)
+	}
+}
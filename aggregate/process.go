@@ -0,0 +1,386 @@
+package aggregate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// ProcessFile reads the measurements file sequentially with ProcessReader.
+// The members of a gzip compressed regular file are decompressed
+// concurrently, up to the size read from the file: fileSize is only kept for
+// compatibility and ignored.
+func ProcessFile(file *os.File, fileSize int64, opts Options) (map[string]*Measurements, error) {
+	opts = opts.withDefaults()
+	p := newProcessor(opts)
+	pool := newBufferPool(opts.PoolSize, opts.ChunkSize)
+	return p.finish(p.readFile(file, pool))
+}
+
+// ProcessReader reads the measurements sequentially from r into a bounded
+// pool of buffers, processes the chunks concurrently and returns the
+// measurements of every station. The size of the input does not need to be
+// known, so r can be stdin, a pipe or a socket. Compressed input is
+// decompressed sequentially.
+func ProcessReader(r io.Reader, opts Options) (map[string]*Measurements, error) {
+	opts = opts.withDefaults()
+	p := newProcessor(opts)
+	pool := newBufferPool(opts.PoolSize, opts.ChunkSize)
+	return p.finish(p.read(r, opts.Compression, pool))
+}
+
+// readFile reads a file with read, decompressing the members of a gzip
+// compressed regular file concurrently.
+func (p *processor) readFile(file *os.File, pool *bufferPool) error {
+	fileStats, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if !fileStats.Mode().IsRegular() {
+		return p.read(file, p.opts.Compression, pool)
+	}
+
+	offset, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	compression, err := detectFile(file, offset, p.opts.Compression)
+	if err != nil {
+		return err
+	}
+	if compression != Gzip {
+		return p.read(file, compression, pool)
+	}
+	reader := newParallelGzipReader(file, offset, fileStats.Size(), p.opts.Workers)
+	defer reader.Close()
+	return p.read(reader, CompressionNone, pool)
+}
+
+// read reads r sequentially into the buffers of pool and dispatches them as
+// chunks of whole lines.
+func (p *processor) read(r io.Reader, compression Compression, pool *bufferPool) error {
+	r, err := decompress(r, compression)
+	if err != nil {
+		return err
+	}
+
+	buffer := pool.get()
+	var carry int
+	for !p.failed.Load() {
+		n, err := readFull(r, buffer[carry:])
+		data := buffer[:carry+n]
+		if err == io.EOF {
+			if len(data) > 0 {
+				p.dispatch(data, pool.releaser(buffer))
+			} else {
+				pool.put(buffer)
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("wasn't able to read chunk of the input: %w", err)
+		}
+
+		// The incomplete line at the end of the chunk is moved to the next buffer
+		lastNewLine := bytes.LastIndexByte(data, '\n')
+		if lastNewLine == -1 {
+			return fmt.Errorf("line longer than the chunk size of %d bytes", p.opts.ChunkSize)
+		}
+		next := pool.get()
+		carry = copy(next, data[lastNewLine+1:])
+
+		p.dispatch(data[:lastNewLine+1], pool.releaser(buffer))
+		buffer = next
+	}
+	return nil
+}
+
+// finish waits for the dispatched chunks and returns their results, or
+// readErr if reading the input failed.
+func (p *processor) finish(readErr error) (map[string]*Measurements, error) {
+	result, err := p.wait()
+	if readErr != nil {
+		return nil, readErr
+	}
+	return result, err
+}
+
+// readFull reads until buffer is full like io.ReadFull, but returns io.EOF
+// whenever the input ended, and io.ErrUnexpectedEOF only if r returned it,
+// like a decompressor does for truncated input.
+func readFull(r io.Reader, buffer []byte) (int, error) {
+	var n int
+	for n < len(buffer) {
+		read, err := r.Read(buffer[n:])
+		n += read
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// chunk is a part of the input made of whole lines.
+type chunk struct {
+	index int
+	// file is the index of the file of ProcessFiles the chunk belongs to
+	file   int
+	offset int64
+	data   []byte
+	// results are the measurements of the chunk, until they are merged
+	results map[string]*Measurements
+	// lines is the number of lines scanned by the worker
+	lines int64
+	// skipped holds the malformed lines skipped in ParseLenient mode
+	skipped *Skipped
+}
+
+// processor hands chunks to the workers and collects their results.
+type processor struct {
+	opts      Options
+	wg        sync.WaitGroup
+	resultsCh chan *chunk
+	errCh     chan error
+	mergedCh  chan []map[string]*Measurements
+	firstCh   chan error
+	chunks    []*chunk
+	// paths are the files of ProcessFiles started so far
+	paths  []string
+	offset int64
+	// failed is set once a worker has reported an error
+	failed atomic.Bool
+}
+
+func newProcessor(opts Options) *processor {
+	p := &processor{
+		opts:      opts,
+		resultsCh: make(chan *chunk, opts.Workers),
+		errCh:     make(chan error, opts.Workers),
+		mergedCh:  make(chan []map[string]*Measurements),
+		firstCh:   make(chan error),
+	}
+	go func() {
+		p.mergedCh <- p.merge()
+	}()
+	go func() {
+		p.firstCh <- firstError(p.errCh)
+	}()
+	return p
+}
+
+// startFile makes the next chunks part of the file at path, at offset 0.
+func (p *processor) startFile(path string) {
+	p.paths = append(p.paths, path)
+	p.offset = 0
+}
+
+// file returns the index of the current file, 0 if no file was started.
+func (p *processor) file() int {
+	if len(p.paths) == 0 {
+		return 0
+	}
+	return len(p.paths) - 1
+}
+
+// dispatch processes data in a new goroutine, release is called as soon as
+// data is not used anymore.
+func (p *processor) dispatch(data []byte, release func()) {
+	c := &chunk{index: len(p.chunks), file: p.file(), offset: p.offset, data: data}
+	p.chunks = append(p.chunks, c)
+	p.offset += int64(len(data))
+
+	p.wg.Add(1)
+	go p.processData(c, release)
+}
+
+// wait waits for all the dispatched chunks and returns the merged results.
+func (p *processor) wait() (map[string]*Measurements, error) {
+	go func() {
+		p.wg.Wait()
+		close(p.resultsCh)
+		close(p.errCh)
+	}()
+
+	merged := <-p.mergedCh
+
+	// Collect and handle errors
+	if err := <-p.firstCh; err != nil {
+		var parseErr *ParseError
+		if errors.As(err, &parseErr) {
+			p.locate(parseErr)
+		}
+		return nil, err
+	}
+
+	if p.opts.Mode == ParseLenient && p.opts.Skipped != nil {
+		for _, c := range p.chunks {
+			for _, sample := range c.skipped.Samples {
+				sample.chunk = c.index
+				p.locate(sample)
+			}
+			p.opts.Skipped.merge(c.skipped, p.opts.MaxSamples)
+		}
+	}
+
+	// only ProcessFiles has paths to report the results of
+	if p.opts.PerFile == nil || len(p.paths) == 0 {
+		if len(merged) == 0 {
+			return make(map[string]*Measurements), nil
+		}
+		return merged[0], nil
+	}
+	result := make(map[string]*Measurements, 200)
+	for i, path := range p.paths {
+		fileResult := make(map[string]*Measurements)
+		if i < len(merged) {
+			fileResult = merged[i]
+		}
+		mergeInto(result, cloneResults(fileResult))
+		// a file given twice was processed twice
+		if existing, ok := p.opts.PerFile[path]; ok {
+			mergeInto(existing, fileResult)
+		} else {
+			p.opts.PerFile[path] = fileResult
+		}
+	}
+	return result, nil
+}
+
+// merge drains resultsCh into one map per file with opts.PerFile, into a
+// single map otherwise.
+func (p *processor) merge() []map[string]*Measurements {
+	var merged []map[string]*Measurements
+	for c := range p.resultsCh {
+		index := 0
+		if p.opts.PerFile != nil {
+			index = c.file
+		}
+		for len(merged) <= index {
+			merged = append(merged, make(map[string]*Measurements, 200))
+		}
+		mergeInto(merged[index], c.results)
+		c.results = nil
+	}
+	return merged
+}
+
+// locate turns the line number of err relative to its chunk into a line
+// number in its file, and sets the path of the file.
+// Line numbers are relative to the chunk until the lines of the previous
+// chunks are known.
+func (p *processor) locate(err *ParseError) {
+	c := p.chunks[err.chunk]
+	for _, previous := range p.chunks[:c.index] {
+		if previous.file == c.file {
+			err.Line += previous.lines
+		}
+	}
+	if c.file < len(p.paths) {
+		err.Path = p.paths[c.file]
+	}
+}
+
+// firstError drains errCh and returns the error closest to the start of the
+// input.
+func firstError(errCh <-chan error) error {
+	var first error
+	for err := range errCh {
+		if first == nil || errorChunk(err) < errorChunk(first) {
+			first = err
+		}
+	}
+	return first
+}
+
+// errorChunk returns the index of the chunk of a ParseError, -1 for other
+// errors. A chunk has at most one error and the chunks are in the order of
+// the input.
+func errorChunk(err error) int {
+	var parseErr *ParseError
+	if errors.As(err, &parseErr) {
+		return parseErr.chunk
+	}
+	return -1
+}
+
+func (p *processor) processData(c *chunk, release func()) {
+	defer p.wg.Done()
+
+	table := p.opts.NewTable()
+	var err error
+	switch p.opts.Mode {
+	case ParseStrict:
+		c.lines, err = scanStrict(c.data, table, p.opts.MaxNameLength)
+	case ParseLenient:
+		c.skipped = newSkipped()
+		c.lines = scanLenient(c.data, table, p.opts.MaxNameLength, c.skipped, p.opts.MaxSamples)
+		for _, sample := range c.skipped.Samples {
+			sample.Offset += c.offset
+		}
+	default:
+		c.lines = scanFast(c.data, table)
+	}
+	c.data = nil
+	release()
+
+	if err != nil {
+		var parseErr *ParseError
+		if errors.As(err, &parseErr) {
+			parseErr.Offset += c.offset
+			parseErr.chunk = c.index
+		}
+		p.failed.Store(true)
+		p.errCh <- err
+	}
+	c.results = table.Map()
+	p.resultsCh <- c
+}
+
+// scanFast adds every line of data to table without validating it and
+// returns the number of lines. Lines without a separator or a temperature
+// are skipped.
+func scanFast(data []byte, table Table) int64 {
+	var lines int64
+
+	i := 0
+	for i < len(data) {
+		// the name is hashed in the same pass that looks for the separator
+		start := i
+		hash := uint64(hashOffset)
+		for i < len(data) && data[i] != ';' && data[i] != '\n' {
+			hash ^= uint64(data[i])
+			hash *= hashPrime
+			i++
+		}
+		if i == len(data) {
+			break
+		}
+		if data[i] == '\n' {
+			lines++
+			i++
+			continue
+		}
+		// the name is used directly from the chunk, the table copies it only for new stations
+		stationName := data[start:i]
+		i++
+
+		temperatureStart := i
+		for i < len(data) && data[i] != '\n' {
+			i++
+		}
+		// the last line of the input may not end with '\n'
+		if i > temperatureStart {
+			table.Add(stationName, hash, ParseTenths(data[temperatureStart:i]))
+			lines++
+		} else if i < len(data) {
+			lines++
+		}
+		i++
+	}
+
+	return lines
+}
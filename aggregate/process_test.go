@@ -0,0 +1,246 @@
+package aggregate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+var scanInput = "Abha;-1.0\nZürich;12.5\nAbha;3.0\nLlanfairpwllgwyngyllgogerychwyrndrobwllllantysiliogogogoch;0.1\nAbha;-2.0"
+
+var scanExpected = map[string]*Measurements{
+	"Abha":   {Min: -20, Max: 30, Sum: 0, Count: 3},
+	"Zürich": {Min: 125, Max: 125, Sum: 125, Count: 1},
+	"Llanfairpwllgwyngyllgogerychwyrndrobwllllantysiliogogogoch": {Min: 1, Max: 1, Sum: 1, Count: 1},
+}
+
+func TestScanFast(t *testing.T) {
+	for _, newTable := range []func() Table{NewMapTable, NewOpenTable} {
+		table := newTable()
+		lines := scanFast([]byte(scanInput), table)
+		if lines != 5 {
+			t.Errorf("scanFast() = %d lines, expected 5", lines)
+		}
+		if got := table.Map(); !reflect.DeepEqual(got, scanExpected) {
+			t.Errorf("scanFast() = %v, expected %v", got, scanExpected)
+		}
+	}
+}
+
+func TestScanFastEmptyLines(t *testing.T) {
+	table := NewMapTable()
+	lines := scanFast([]byte("a;1.0\n\nb;\n\na;2.0\n\n"), table)
+	if lines != 6 {
+		t.Errorf("scanFast() = %d lines, expected 6", lines)
+	}
+	expected := map[string]*Measurements{"a": {Min: 10, Max: 20, Sum: 30, Count: 2}}
+	if got := table.Map(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("scanFast() = %v, expected %v", got, expected)
+	}
+}
+
+func TestOpenTableGrow(t *testing.T) {
+	table := newOpenTable(4)
+	for i := 0; i < 1000; i++ {
+		name := []byte(strings.Repeat("x", i%100) + string(rune('a'+i/100)))
+		table.Add(name, HashName(name), int64(i))
+	}
+	if got := len(table.Map()); got != 1000 {
+		t.Errorf("open table has %d stations, expected 1000", got)
+	}
+}
+
+func TestScanStrict(t *testing.T) {
+	table := NewMapTable()
+	lines, err := scanStrict([]byte(scanInput), table, DefaultMaxNameLength)
+	if err != nil || lines != 5 {
+		t.Fatalf("scanStrict() = %d, %v", lines, err)
+	}
+	if got := table.Map(); !reflect.DeepEqual(got, scanExpected) {
+		t.Errorf("scanStrict() = %v, expected %v", got, scanExpected)
+	}
+
+	tests := []struct {
+		input    string
+		expected error
+		line     int64
+		offset   int64
+	}{
+		{"a;1.0\nb 1.0\n", ErrMissingSeparator, 2, 6},
+		{"a;1.0\n\n", ErrMissingSeparator, 2, 6},
+		{"a;1\n", ErrBadNumber, 1, 0},
+		{"a;1.05\n", ErrBadNumber, 1, 0},
+		{"a;100.0\n", ErrBadNumber, 1, 0},
+		{"a;1.0\nb;-\n", ErrBadNumber, 2, 6},
+		{"a;1.0\na;1.0;2.0\n", ErrBadNumber, 2, 6},
+		{"a;1.0\n" + strings.Repeat("é", 51) + ";1.0\n", ErrNameTooLong, 2, 6},
+		{"a;1.0\na;2.0\n\xff;1.0\n", ErrInvalidUTF8, 3, 12},
+	}
+	for _, test := range tests {
+		_, err := scanStrict([]byte(test.input), NewMapTable(), DefaultMaxNameLength)
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) || !errors.Is(err, test.expected) {
+			t.Errorf("scanStrict(%q) = %v, expected %v", test.input, err, test.expected)
+			continue
+		}
+		if parseErr.Line != test.line || parseErr.Offset != test.offset {
+			t.Errorf("scanStrict(%q) failed at line %d offset %d, expected line %d offset %d",
+				test.input, parseErr.Line, parseErr.Offset, test.line, test.offset)
+		}
+	}
+}
+
+func TestScanLenient(t *testing.T) {
+	input := "a;1.0\nb 1.0\na;x\nb;2.0\nc;1\n"
+	skipped := newSkipped()
+	table := NewMapTable()
+	lines := scanLenient([]byte(input), table, DefaultMaxNameLength, skipped, 2)
+	if lines != 5 {
+		t.Errorf("scanLenient() = %d lines, expected 5", lines)
+	}
+	if len(table.Map()) != 2 {
+		t.Errorf("scanLenient() kept %v", table.Map())
+	}
+	if skipped.Total() != 3 || skipped.Counts[ErrBadNumber] != 2 || skipped.Counts[ErrMissingSeparator] != 1 {
+		t.Errorf("skipped counts = %v", skipped.Counts)
+	}
+	if len(skipped.Samples) != 2 || skipped.Samples[0].Text != "b 1.0" || skipped.Samples[1].Offset != 12 {
+		t.Errorf("skipped samples = %v", skipped.Samples)
+	}
+}
+
+func TestProcessFileReportsGlobalLine(t *testing.T) {
+	var input bytes.Buffer
+	for i := 0; i < 1000; i++ {
+		input.WriteString("Station;12.3\n")
+	}
+	offset := input.Len()
+	input.WriteString("Station;bad\n")
+	input.WriteString("Station 1.0\n")
+
+	path := t.TempDir() + "/measurements.txt"
+	if err := os.WriteFile(path, input.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	for _, processFile := range []func(*os.File, int64, Options) (map[string]*Measurements, error){ProcessFile, ProcessFileMmap} {
+		if _, err := file.Seek(0, 0); err != nil {
+			t.Fatal(err)
+		}
+		_, err = processFile(file, int64(input.Len()), Options{Workers: 4, ChunkSize: 100, Mode: ParseStrict})
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Fatalf("expected a ParseError, got %v", err)
+		}
+		if parseErr.Line != 1001 || parseErr.Offset != int64(offset) || parseErr.Err != ErrBadNumber {
+			t.Errorf("got %v, expected line 1001 (byte offset %d)", parseErr, offset)
+		}
+	}
+}
+
+func TestProcessFileMmapIgnoresSize(t *testing.T) {
+	input := "Abha;12.3\nAbha;14.1\n"
+	path := t.TempDir() + "/measurements.txt"
+	if err := os.WriteFile(path, []byte(input), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	expected := map[string]*Measurements{"Abha": {Min: 123, Max: 141, Sum: 264, Count: 2}}
+	for _, size := range []int64{0, 8, int64(len(input)), 1 << 20} {
+		results, err := ProcessFileMmap(file, size, Options{Workers: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(results, expected) {
+			t.Errorf("size %d: got %v, expected %v", size, results, expected)
+		}
+	}
+}
+
+func TestProcessReaderPipe(t *testing.T) {
+	var input bytes.Buffer
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&input, "Station%d;%d.%d\n", i%7, i%50-25, i%10)
+	}
+	expected, err := ProcessReader(bytes.NewReader(input.Bytes()), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a pipe returns short reads of unknown total size
+	reader, writer := io.Pipe()
+	go func() {
+		data := input.Bytes()
+		for len(data) > 0 {
+			n := 1 + len(data)%37
+			if n > len(data) {
+				n = len(data)
+			}
+			writer.Write(data[:n])
+			data = data[n:]
+		}
+		writer.Close()
+	}()
+	results, err := ProcessReader(iotest.HalfReader(reader), Options{Workers: 3, ChunkSize: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("got %v, expected %v", results, expected)
+	}
+}
+
+func TestProcessReaderPoolSize(t *testing.T) {
+	var input bytes.Buffer
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&input, "Station%d;%d.%d\n", i%7, i%50-25, i%10)
+	}
+	expected, err := ProcessReader(bytes.NewReader(input.Bytes()), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// fewer buffers than workers limit the chunks processed at the same time
+	for _, poolSize := range []int{1, 2, 3, 10} {
+		results, err := ProcessReader(bytes.NewReader(input.Bytes()), Options{Workers: 4, ChunkSize: 64, PoolSize: poolSize})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(results, expected) {
+			t.Errorf("pool size %d: got %v, expected %v", poolSize, results, expected)
+		}
+	}
+}
+
+func TestSplitChunks(t *testing.T) {
+	data := []byte("a;1.0\nbb;2.0\nccc;3.0\ndddd;4.0\n")
+	for n := 1; n <= 10; n++ {
+		chunks := splitChunks(data, n)
+		if len(chunks) > n {
+			t.Errorf("splitChunks(%d) returned %d chunks", n, len(chunks))
+		}
+		if joined := bytes.Join(chunks, nil); !bytes.Equal(joined, data) {
+			t.Errorf("splitChunks(%d) lost data: %q", n, joined)
+		}
+		for _, chunk := range chunks {
+			if chunk[len(chunk)-1] != '\n' {
+				t.Errorf("splitChunks(%d) split a line: %q", n, chunk)
+			}
+		}
+	}
+}
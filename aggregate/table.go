@@ -0,0 +1,161 @@
+package aggregate
+
+import "unsafe"
+
+// Table accumulates the measurements of the stations found in a chunk.
+// A Table is used by a single goroutine.
+type Table interface {
+	// Add records a temperature, in tenths of a degree, for the station.
+	// hash is HashName(name). name is only valid during the call.
+	Add(name []byte, hash uint64, temperature int64)
+	// Map returns the measurements of every station added to the table.
+	Map() map[string]*Measurements
+}
+
+// Tables maps the name of every Table implementation to its constructor.
+var Tables = map[string]func() Table{
+	"map":  NewMapTable,
+	"open": NewOpenTable,
+}
+
+// FNV-1a parameters of HashName.
+const (
+	hashOffset = 14695981039346656037
+	hashPrime  = 1099511628211
+)
+
+// HashName returns the FNV-1a hash of a station name.
+func HashName(name []byte) uint64 {
+	var hash uint64 = hashOffset
+	for _, b := range name {
+		hash ^= uint64(b)
+		hash *= hashPrime
+	}
+	return hash
+}
+
+func newMeasurements(temperature int64) Measurements {
+	return Measurements{
+		Min:   temperature,
+		Max:   temperature,
+		Sum:   temperature,
+		Count: 1,
+	}
+}
+
+func (m *Measurements) add(temperature int64) {
+	m.Count++
+	m.Sum += temperature
+	if temperature < m.Min {
+		m.Min = temperature
+	}
+	if temperature > m.Max {
+		m.Max = temperature
+	}
+}
+
+// mapTable is a Table backed by the built-in map.
+type mapTable map[string]*Measurements
+
+// NewMapTable returns a Table backed by the built-in map. It ignores the hash.
+func NewMapTable() Table {
+	return make(mapTable, 5000)
+}
+
+func (t mapTable) Add(name []byte, hash uint64, temperature int64) {
+	// string without copy to perform the lookup
+	nameUnsafe := unsafe.String(unsafe.SliceData(name), len(name))
+	existingStation, ok := t[nameUnsafe]
+	if !ok {
+		measurements := newMeasurements(temperature)
+		t[string(name)] = &measurements
+		return
+	}
+	existingStation.add(temperature)
+}
+
+func (t mapTable) Map() map[string]*Measurements {
+	return t
+}
+
+type openEntry struct {
+	hash         uint64
+	name         string
+	used         bool
+	measurements Measurements
+}
+
+// openTable is an open-addressing hash table with linear probing keyed on
+// the raw station bytes.
+type openTable struct {
+	entries []openEntry
+	mask    uint64
+	count   int
+}
+
+// NewOpenTable returns a Table using open addressing with linear probing.
+// It uses the hash passed to Add, so no hashing happens during the lookup.
+func NewOpenTable() Table {
+	return newOpenTable(1 << 13)
+}
+
+func newOpenTable(size int) *openTable {
+	return &openTable{
+		entries: make([]openEntry, size),
+		mask:    uint64(size - 1),
+	}
+}
+
+func (t *openTable) Add(name []byte, hash uint64, temperature int64) {
+	index := hash & t.mask
+	for {
+		entry := &t.entries[index]
+		if !entry.used {
+			*entry = openEntry{
+				hash:         hash,
+				name:         string(name),
+				used:         true,
+				measurements: newMeasurements(temperature),
+			}
+			t.count++
+			// keep the load factor under 1/2 so that probe sequences stay short
+			if t.count*2 > len(t.entries) {
+				t.grow()
+			}
+			return
+		}
+		if entry.hash == hash && entry.name == string(name) {
+			entry.measurements.add(temperature)
+			return
+		}
+		index = (index + 1) & t.mask
+	}
+}
+
+func (t *openTable) grow() {
+	bigger := newOpenTable(len(t.entries) * 2)
+	for i := range t.entries {
+		entry := &t.entries[i]
+		if !entry.used {
+			continue
+		}
+		index := entry.hash & bigger.mask
+		for bigger.entries[index].used {
+			index = (index + 1) & bigger.mask
+		}
+		bigger.entries[index] = *entry
+	}
+	bigger.count = t.count
+	*t = *bigger
+}
+
+func (t *openTable) Map() map[string]*Measurements {
+	result := make(map[string]*Measurements, t.count)
+	for i := range t.entries {
+		entry := &t.entries[i]
+		if entry.used {
+			result[entry.name] = &entry.measurements
+		}
+	}
+	return result
+}
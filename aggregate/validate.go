@@ -0,0 +1,204 @@
+package aggregate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// DefaultMaxNameLength is the maximum length in bytes of a station name
+// allowed by the challenge.
+const DefaultMaxNameLength = 100
+
+// ParseMode selects how malformed lines are handled.
+type ParseMode int
+
+const (
+	// ParseFast assumes every line is well formed and does not validate it.
+	ParseFast ParseMode = iota
+	// ParseStrict validates every line and fails on the first malformed one.
+	ParseStrict
+	// ParseLenient validates every line and skips the malformed ones,
+	// recording them in Options.Skipped.
+	ParseLenient
+)
+
+// Errors wrapped by ParseError describing why a line is malformed.
+var (
+	ErrMissingSeparator = errors.New("missing ';' separator")
+	ErrBadNumber        = errors.New("temperature is not a number in [-99.9, 99.9] with one decimal digit")
+	ErrNameTooLong      = errors.New("station name longer than the maximum length")
+	ErrInvalidUTF8      = errors.New("station name is not valid UTF-8")
+)
+
+// ParseErrors lists the errors a ParseError can wrap.
+var ParseErrors = []error{ErrMissingSeparator, ErrBadNumber, ErrNameTooLong, ErrInvalidUTF8}
+
+// ParseError reports a malformed line.
+type ParseError struct {
+	// Offset is the byte offset of the start of the line in the input.
+	Offset int64
+	// Line is the 1-based line number.
+	Line int64
+	// Err is one of ParseErrors.
+	Err error
+	// Text is the content of the line, truncated to maxSampleText bytes.
+	Text string
+	// Path is the file of ProcessFiles the line belongs to, empty for the
+	// other functions.
+	Path string
+
+	// chunk is the index of the chunk the line belongs to
+	chunk int
+}
+
+func (e *ParseError) Error() string {
+	if e.Path != "" {
+		return fmt.Sprintf("%s: line %d (byte offset %d): %v", e.Path, e.Line, e.Offset, e.Err)
+	}
+	return fmt.Sprintf("line %d (byte offset %d): %v", e.Line, e.Offset, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// maxSampleText is the maximum length of the text kept for a skipped line.
+const maxSampleText = 120
+
+// Skipped reports the malformed lines skipped in ParseLenient mode.
+type Skipped struct {
+	// Counts is the number of skipped lines per error of ParseErrors.
+	Counts map[error]int64
+	// Samples holds the first skipped lines, in the order of the input.
+	Samples []*ParseError
+}
+
+func newSkipped() *Skipped {
+	return &Skipped{Counts: make(map[error]int64, len(ParseErrors))}
+}
+
+// Total returns the number of skipped lines.
+func (s *Skipped) Total() int64 {
+	var total int64
+	for _, count := range s.Counts {
+		total += count
+	}
+	return total
+}
+
+func (s *Skipped) merge(other *Skipped, maxSamples int) {
+	if s.Counts == nil {
+		s.Counts = make(map[error]int64, len(ParseErrors))
+	}
+	for err, count := range other.Counts {
+		s.Counts[err] += count
+	}
+	for _, sample := range other.Samples {
+		if len(s.Samples) >= maxSamples {
+			break
+		}
+		s.Samples = append(s.Samples, sample)
+	}
+}
+
+// scanStrict adds every line of data to table and stops at the first
+// malformed line. It returns the number of lines scanned.
+func scanStrict(data []byte, table Table, maxNameLength int) (int64, error) {
+	var firstErr *ParseError
+	lines := scanLines(data, table, maxNameLength, func(err *ParseError) bool {
+		firstErr = err
+		return false
+	})
+	if firstErr != nil {
+		return lines, firstErr
+	}
+	return lines, nil
+}
+
+// scanLenient adds every well formed line of data to table and records the
+// malformed ones in skipped. It returns the number of lines scanned.
+func scanLenient(data []byte, table Table, maxNameLength int, skipped *Skipped, maxSamples int) int64 {
+	return scanLines(data, table, maxNameLength, func(err *ParseError) bool {
+		skipped.Counts[err.Err]++
+		if len(skipped.Samples) < maxSamples {
+			skipped.Samples = append(skipped.Samples, err)
+		}
+		return true
+	})
+}
+
+// scanLines adds every well formed line of data to table. onError is called
+// for every malformed line and the scan stops when it returns false.
+// Station names longer than maxNameLength bytes are malformed.
+// It returns the number of lines scanned, a last line without '\n' included.
+func scanLines(data []byte, table Table, maxNameLength int, onError func(err *ParseError) bool) int64 {
+	var lines int64
+	var offset int
+	for offset < len(data) {
+		line := data[offset:]
+		end := bytes.IndexByte(line, '\n')
+		if end != -1 {
+			line = line[:end]
+		}
+		lines++
+
+		name, temperature, err := parseLine(line, maxNameLength)
+		if err != nil {
+			text := line
+			if len(text) > maxSampleText {
+				text = text[:maxSampleText]
+			}
+			parseErr := &ParseError{Offset: int64(offset), Line: lines, Err: err, Text: string(text)}
+			if !onError(parseErr) {
+				return lines
+			}
+		} else {
+			table.Add(name, HashName(name), temperature)
+		}
+
+		offset += len(line) + 1
+	}
+	return lines
+}
+
+// parseLine splits a line into the station name and the temperature in tenths
+// of a degree.
+func parseLine(line []byte, maxNameLength int) ([]byte, int64, error) {
+	separator := bytes.IndexByte(line, ';')
+	if separator == -1 {
+		return nil, 0, ErrMissingSeparator
+	}
+	name := line[:separator]
+	if len(name) > maxNameLength {
+		return nil, 0, ErrNameTooLong
+	}
+	if !utf8.Valid(name) {
+		return nil, 0, ErrInvalidUTF8
+	}
+	temperature := line[separator+1:]
+	if !validTemperature(temperature) {
+		return nil, 0, ErrBadNumber
+	}
+	return name, ParseTenths(temperature), nil
+}
+
+// validTemperature reports whether b matches -?\d{1,2}\.\d
+func validTemperature(b []byte) bool {
+	if len(b) > 0 && b[0] == '-' {
+		b = b[1:]
+	}
+	if len(b) < 3 || len(b) > 4 || b[len(b)-2] != '.' {
+		return false
+	}
+	for i, c := range b {
+		if i == len(b)-2 {
+			continue
+		}
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
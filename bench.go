@@ -0,0 +1,206 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"1brc/aggregate"
+	"1brc/generate"
+)
+
+// benchCase is one combination of the processing strategies.
+type benchCase struct {
+	mode      string
+	table     string
+	workers   int
+	chunkSize int
+}
+
+// benchResult holds the measurements of a benchCase.
+type benchResult struct {
+	duration time.Duration
+	rows     int64
+	mallocs  uint64
+	bytes    uint64
+}
+
+func runBench(args []string) int {
+	flags := flag.NewFlagSet("bench", flag.ContinueOnError)
+	input := flags.String("input", "", "measurements file to process, generated in a temporary directory if empty")
+	rows := flags.Int("rows", 10000000, "number of rows of the generated file")
+	modes := flags.String("modes", "reader,mmap", "comma separated list of modes")
+	tables := flags.String("tables", "map,open", "comma separated list of tables")
+	workers := flags.String("workers", "1,"+strconv.Itoa(runtime.GOMAXPROCS(0)), "comma separated list of worker counts")
+	chunkSizes := flags.String("chunk-sizes", "1,8,30", "comma separated list of chunk sizes in MB, reader mode only")
+	runs := flags.Int("runs", 3, "number of runs of every case, the fastest is reported")
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	workerCounts, err := parseInts(*workers)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid workers:", err)
+		return exitUsage
+	}
+	chunkSizeList, err := parseInts(*chunkSizes)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid chunk-sizes:", err)
+		return exitUsage
+	}
+	if *runs < 1 {
+		fmt.Fprintln(os.Stderr, "runs must be >= 1")
+		return exitUsage
+	}
+
+	var cases []benchCase
+	for _, mode := range strings.Split(*modes, ",") {
+		if mode != "reader" && mode != "mmap" {
+			fmt.Fprintf(os.Stderr, "unknown mode %q\n", mode)
+			return exitUsage
+		}
+		for _, table := range strings.Split(*tables, ",") {
+			if _, ok := aggregate.Tables[table]; !ok {
+				fmt.Fprintf(os.Stderr, "unknown table %q\n", table)
+				return exitUsage
+			}
+			for _, w := range workerCounts {
+				if mode == "mmap" {
+					cases = append(cases, benchCase{mode: mode, table: table, workers: w})
+					continue
+				}
+				for _, chunkSize := range chunkSizeList {
+					cases = append(cases, benchCase{mode: mode, table: table, workers: w, chunkSize: chunkSize})
+				}
+			}
+		}
+	}
+
+	path := *input
+	if path == "" {
+		dir, err := os.MkdirTemp("", "1brc-bench")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		defer os.RemoveAll(dir)
+		path = filepath.Join(dir, "measurements.txt")
+		fmt.Fprintf(os.Stderr, "Generating %d rows in %s\n", *rows, path)
+		if _, err := generate.MeasurementFile(path, *rows, generate.Options{}); err != nil {
+			fmt.Fprintln(os.Stderr, "Error during file generation:", err)
+			return exitError
+		}
+	}
+	fileStats, err := os.Stat(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	megabytes := float64(fileStats.Size()) / (1024 * 1024)
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(writer, "mode\ttable\tworkers\tchunk MB\ttime\trows/s\tMB/s\tallocs\talloc MB\t")
+	for _, c := range cases {
+		var best benchResult
+		for i := 0; i < *runs; i++ {
+			result, err := runBenchCase(path, c)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Processing failed:", err)
+				return exitError
+			}
+			if i == 0 || result.duration < best.duration {
+				best = result
+			}
+		}
+		chunk := "-"
+		if c.chunkSize > 0 {
+			chunk = strconv.Itoa(c.chunkSize)
+		}
+		seconds := best.duration.Seconds()
+		fmt.Fprintf(writer, "%s\t%s\t%d\t%s\t%v\t%.0f\t%.1f\t%d\t%.1f\t\n",
+			c.mode, c.table, c.workers, chunk,
+			best.duration.Round(time.Millisecond),
+			float64(best.rows)/seconds,
+			megabytes/seconds,
+			best.mallocs,
+			float64(best.bytes)/(1024*1024),
+		)
+	}
+	writer.Flush()
+
+	return exitOK
+}
+
+func runBenchCase(path string, c benchCase) (benchResult, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return benchResult{}, err
+	}
+	defer file.Close()
+	fileStats, err := file.Stat()
+	if err != nil {
+		return benchResult{}, err
+	}
+
+	opts := aggregate.Options{
+		Workers:   c.workers,
+		ChunkSize: c.chunkSize * 1024 * 1024,
+		NewTable:  aggregate.Tables[c.table],
+	}
+	processFile := aggregate.ProcessFile
+	if c.mode == "mmap" {
+		processFile = aggregate.ProcessFileMmap
+	}
+
+	runtime.GC()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	startTime := time.Now()
+	results, err := processFile(file, fileStats.Size(), opts)
+	duration := time.Since(startTime)
+	runtime.ReadMemStats(&after)
+	if err != nil {
+		return benchResult{}, err
+	}
+
+	var rows int64
+	for _, measurements := range results {
+		rows += measurements.Count
+	}
+	return benchResult{
+		duration: duration,
+		rows:     rows,
+		mallocs:  after.Mallocs - before.Mallocs,
+		bytes:    after.TotalAlloc - before.TotalAlloc,
+	}, nil
+}
+
+// parseInts parses a comma separated list of positive integers, dropping
+// the duplicates.
+func parseInts(list string) ([]int, error) {
+	var values []int
+	seen := make(map[int]bool)
+	for _, field := range strings.Split(list, ",") {
+		value, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, err
+		}
+		if value < 1 {
+			return nil, fmt.Errorf("%d must be >= 1", value)
+		}
+		if !seen[value] {
+			seen[value] = true
+			values = append(values, value)
+		}
+	}
+	return values, nil
+}
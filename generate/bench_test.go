@@ -0,0 +1,55 @@
+package generate
+
+import (
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"1brc/round"
+)
+
+func BenchmarkFormatRow(b *testing.B) {
+	stations := Stations()
+	randomGenerator := rand.New(rand.NewSource(1))
+	temperatures := make([]float64, 1024)
+	for i := range temperatures {
+		temperatures[i] = stations[i%len(stations)].temperature(randomGenerator, round.HalfUp, 0)
+	}
+
+	// Sprint is how the rows were formatted before appendRow
+	b.Run("Sprint", func(b *testing.B) {
+		b.ReportAllocs()
+		buffer := make([]byte, 0, 64*1024)
+		for i := 0; i < b.N; i++ {
+			if len(buffer) > 60*1024 {
+				buffer = buffer[:0]
+			}
+			station := stations[i%len(stations)]
+			buffer = append(buffer, fmt.Sprint(station.id, ";", temperatures[i%len(temperatures)], "\n")...)
+		}
+	})
+	b.Run("appendRow", func(b *testing.B) {
+		b.ReportAllocs()
+		buffer := make([]byte, 0, 64*1024)
+		for i := 0; i < b.N; i++ {
+			if len(buffer) > 60*1024 {
+				buffer = buffer[:0]
+			}
+			station := stations[i%len(stations)]
+			buffer = appendRow(buffer, station.id, temperatures[i%len(temperatures)])
+		}
+	})
+}
+
+func BenchmarkMeasurementFile(b *testing.B) {
+	const rows = 1000000
+	path := filepath.Join(b.TempDir(), "measurements.txt")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := MeasurementFile(path, rows, Options{Seed: 1}); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(rows)*float64(b.N)/b.Elapsed().Seconds(), "rows/s")
+}
@@ -0,0 +1,267 @@
+package generate
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LoadCatalog reads the stations of a catalog file, in JSON if its extension
+// is .json and in CSV otherwise. See ReadCatalogCSV and ReadCatalogJSON for
+// the formats.
+func LoadCatalog(path string) ([]*Station, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var stations []*Station
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		stations, err = ReadCatalogJSON(file)
+	} else {
+		stations, err = ReadCatalogCSV(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return stations, nil
+}
+
+// catalogColumns are the columns of a CSV catalog without a header, in order.
+var catalogColumns = []string{"name", "mean", "stddev", "distribution", "skew", "amplitude", "cycles", "min", "max", "weight"}
+
+// ReadCatalogCSV reads stations from comma separated
+// "name,mean[,stddev[,distribution[,skew[,amplitude[,cycles[,min[,max[,weight]]]]]]]]"
+// records. A first record starting with "name" is a header, which can list
+// the columns in any order; lines starting with '#' are comments. Missing or
+// empty fields take the defaults of the Model, the standard deviation
+// defaults to DefaultStdDev, the distribution to normal and the weight to 1.
+func ReadCatalogCSV(r io.Reader) ([]*Station, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	columns := catalogColumns
+	var stations []*Station
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		if len(stations) == 0 && strings.EqualFold(record[0], "name") {
+			columns, err = headerColumns(record)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			continue
+		}
+		if len(record) < 2 || len(record) > len(columns) {
+			return nil, fmt.Errorf("line %d: expected name,mean[,%s], got %d fields", line, strings.Join(columns[2:], ","), len(record))
+		}
+
+		var entry catalogEntry
+		for i, field := range record {
+			if err := entry.set(columns[i], field); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+		station, err := entry.station()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		stations = append(stations, station)
+	}
+
+	if err := ValidateStations(stations); err != nil {
+		return nil, err
+	}
+	return stations, nil
+}
+
+// headerColumns checks the column names of a CSV header.
+func headerColumns(header []string) ([]string, error) {
+	columns := make([]string, len(header))
+	seen := make(map[string]bool, len(header))
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(column))
+		known := false
+		for _, name := range catalogColumns {
+			known = known || name == column
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown column %q, expected %s", column, strings.Join(catalogColumns, ", "))
+		}
+		if seen[column] {
+			return nil, fmt.Errorf("duplicate column %q", column)
+		}
+		seen[column] = true
+		columns[i] = column
+	}
+	if !seen["mean"] {
+		return nil, errors.New("missing mean column")
+	}
+	return columns, nil
+}
+
+// catalogEntry is a station of a catalog, the fields that are nil take
+// their defaults.
+type catalogEntry struct {
+	Name         string   `json:"name"`
+	Mean         *float64 `json:"mean"`
+	StdDev       *float64 `json:"stddev"`
+	Distribution string   `json:"distribution"`
+	Skew         float64  `json:"skew"`
+	Amplitude    float64  `json:"amplitude"`
+	Cycles       float64  `json:"cycles"`
+	Min          *float64 `json:"min"`
+	Max          *float64 `json:"max"`
+	Weight       *float64 `json:"weight"`
+}
+
+// set sets the field of the CSV column to value, empty values are ignored.
+func (e *catalogEntry) set(column, value string) error {
+	if column == "name" {
+		e.Name = value
+		return nil
+	}
+	if value == "" {
+		return nil
+	}
+	if column == "distribution" {
+		e.Distribution = value
+		return nil
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", column, err)
+	}
+	switch column {
+	case "mean":
+		e.Mean = &number
+	case "stddev":
+		e.StdDev = &number
+	case "skew":
+		e.Skew = number
+	case "amplitude":
+		e.Amplitude = number
+	case "cycles":
+		e.Cycles = number
+	case "min":
+		e.Min = &number
+	case "max":
+		e.Max = &number
+	case "weight":
+		e.Weight = &number
+	}
+	return nil
+}
+
+// station returns the station of the entry with the defaults applied.
+func (e *catalogEntry) station() (*Station, error) {
+	if e.Mean == nil {
+		return nil, errors.New("missing mean")
+	}
+	model := Model{
+		StdDev:    DefaultStdDev,
+		Skew:      e.Skew,
+		Amplitude: e.Amplitude,
+		Cycles:    e.Cycles,
+	}
+	if e.StdDev != nil {
+		model.StdDev = *e.StdDev
+	}
+	if e.Distribution != "" {
+		distribution, err := ParseDistribution(e.Distribution)
+		if err != nil {
+			return nil, err
+		}
+		model.Distribution = distribution
+	}
+	// a single bound keeps the range of the challenge on the other side
+	if e.Min != nil || e.Max != nil {
+		model.Clamp = true
+		model.Min, model.Max = MinTemperature, MaxTemperature
+		if e.Min != nil {
+			model.Min = *e.Min
+		}
+		if e.Max != nil {
+			model.Max = *e.Max
+		}
+	}
+	station := NewStationModel(e.Name, *e.Mean, model)
+	if e.Weight != nil {
+		station.SetWeight(*e.Weight)
+	}
+	return station, nil
+}
+
+// ReadCatalogJSON reads stations from an array of objects with the name and
+// mean fields and the optional stddev, distribution, skew, amplitude, cycles,
+// min, max and weight fields, with the same defaults as ReadCatalogCSV.
+func ReadCatalogJSON(r io.Reader) ([]*Station, error) {
+	var entries []catalogEntry
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&entries); err != nil {
+		return nil, err
+	}
+
+	stations := make([]*Station, 0, len(entries))
+	for i, entry := range entries {
+		station, err := entry.station()
+		if err != nil {
+			return nil, fmt.Errorf("station %d: %w", i+1, err)
+		}
+		stations = append(stations, station)
+	}
+
+	if err := ValidateStations(stations); err != nil {
+		return nil, err
+	}
+	return stations, nil
+}
+
+// ValidateStations checks that there is at least one station, that the names
+// are unique, not empty and cannot break a row, that the means are finite
+// and that the models and weights are valid.
+func ValidateStations(stations []*Station) error {
+	if len(stations) == 0 {
+		return errors.New("no stations")
+	}
+	names := make(map[string]int, len(stations))
+	for i, station := range stations {
+		if station.id == "" {
+			return fmt.Errorf("station %d: empty name", i+1)
+		}
+		if strings.ContainsAny(station.id, ";\n") {
+			return fmt.Errorf("station %d: name %q contains ';' or a new line", i+1, station.id)
+		}
+		if first, ok := names[station.id]; ok {
+			return fmt.Errorf("station %d: duplicate name %q, first used by station %d", i+1, station.id, first)
+		}
+		names[station.id] = i + 1
+		if math.IsNaN(station.meanTemperature) || math.IsInf(station.meanTemperature, 0) {
+			return fmt.Errorf("station %d: invalid mean %v", i+1, station.meanTemperature)
+		}
+		if err := station.model.validate(); err != nil {
+			return fmt.Errorf("station %d: %w", i+1, err)
+		}
+		if !(station.weight >= 0) || math.IsInf(station.weight, 0) {
+			return fmt.Errorf("station %d: invalid weight %v", i+1, station.weight)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package generate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadCatalogCSV(t *testing.T) {
+	input := `# sensors
+name,mean,stddev
+Abha,18.0,3.5
+"Washington, D.C.",14.6
+Yakutsk,-8.8,
+`
+	stations, err := ReadCatalogCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []*Station{
+		NewStationStdDev("Abha", 18.0, 3.5),
+		NewStationStdDev("Washington, D.C.", 14.6, DefaultStdDev),
+		NewStationStdDev("Yakutsk", -8.8, DefaultStdDev),
+	}
+	if len(stations) != len(expected) {
+		t.Fatalf("got %d stations, expected %d", len(stations), len(expected))
+	}
+	for i := range expected {
+		if *stations[i] != *expected[i] {
+			t.Errorf("station %d = %+v, expected %+v", i, *stations[i], *expected[i])
+		}
+	}
+}
+
+func TestReadCatalogJSON(t *testing.T) {
+	input := `[{"name": "Abha", "mean": 18.0, "stddev": 3.5}, {"name": "Zürich", "mean": 9.3}]`
+	stations, err := ReadCatalogJSON(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stations) != 2 || stations[0].StdDev() != 3.5 || stations[1].StdDev() != DefaultStdDev || stations[1].Name() != "Zürich" {
+		t.Errorf("got %+v %+v", *stations[0], *stations[1])
+	}
+}
+
+func TestReadCatalogModels(t *testing.T) {
+	input := `name,distribution,mean,amplitude,min
+Yakutsk,seasonal,-8.8,30,
+Abha,,18.0,,5
+`
+	stations, err := ReadCatalogCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []*Station{
+		NewStationModel("Yakutsk", -8.8, Model{Distribution: Seasonal, StdDev: DefaultStdDev, Amplitude: 30}),
+		NewStationModel("Abha", 18.0, Model{StdDev: DefaultStdDev, Clamp: true, Min: 5, Max: MaxTemperature}),
+	}
+	for i := range expected {
+		if *stations[i] != *expected[i] {
+			t.Errorf("station %d = %+v, expected %+v", i, *stations[i], *expected[i])
+		}
+	}
+
+	input = `[{"name": "Abha", "mean": 18.0, "stddev": 3.5, "distribution": "skew-normal", "skew": 4, "max": 40}]`
+	stations, err = ReadCatalogJSON(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := Model{Distribution: SkewNormal, StdDev: 3.5, Skew: 4, Clamp: true, Min: MinTemperature, Max: 40}
+	if stations[0].Model() != model {
+		t.Errorf("model = %+v, expected %+v", stations[0].Model(), model)
+	}
+
+	input = `[{"name": "Frozen", "mean": 0, "min": 0, "max": 0}]`
+	stations, err = ReadCatalogJSON(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	model = Model{StdDev: DefaultStdDev, Clamp: true}
+	if stations[0].Model() != model {
+		t.Errorf("model = %+v, expected %+v", stations[0].Model(), model)
+	}
+}
+
+func TestReadCatalogErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", "no stations"},
+		{"Abha,18.0\nAbha,19.0\n", "duplicate name"},
+		{",18.0\n", "empty name"},
+		{"Abha;x,18.0\n", "contains ';'"},
+		{"Abha\n", "expected name,mean"},
+		{"Abha,warm\n", "invalid mean"},
+		{"Abha,18.0,-1\n", "invalid stddev"},
+		{"Abha,18.0,5,gaussian\n", "unknown distribution"},
+		{"Abha,18.0,5,normal,0,0,0,30,20\n", "min 30 is greater than max 20"},
+		{"Abha,18.0,5,seasonal,0,10,-1\n", "invalid cycles"},
+		{"name,mean,humidity\nAbha,18.0,40\n", "unknown column"},
+		{"name,stddev\nAbha,5\n", "missing mean column"},
+	}
+	for _, test := range tests {
+		_, err := ReadCatalogCSV(strings.NewReader(test.input))
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("ReadCatalogCSV(%q) = %v, expected an error containing %q", test.input, err, test.expected)
+		}
+	}
+
+	if _, err := ReadCatalogJSON(strings.NewReader(`[{"name": "Abha"}]`)); err == nil {
+		t.Error("ReadCatalogJSON without mean should fail")
+	}
+}
+
+func TestBuiltInStationsAreValid(t *testing.T) {
+	if err := ValidateStations(Stations()); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,160 @@
+package generate
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Distribution is the shape of the temperatures of a station around its mean.
+type Distribution int
+
+const (
+	// Normal draws normally distributed temperatures.
+	Normal Distribution = iota
+	// Uniform draws temperatures uniformly between mean-√3·StdDev and
+	// mean+√3·StdDev, which have the standard deviation StdDev.
+	Uniform
+	// SkewNormal draws skew-normal temperatures with the shape Skew, shifted
+	// and scaled so that they keep the mean and the standard deviation StdDev.
+	// A positive Skew gives a long tail of hot days.
+	SkewNormal
+	// Seasonal adds a sinusoid of amplitude Amplitude over the simulated time
+	// axis to normally distributed temperatures. The time axis goes from the
+	// first row of the file to the last one, over which the sinusoid makes
+	// Cycles periods.
+	Seasonal
+)
+
+var distributionNames = map[Distribution]string{
+	Normal:     "normal",
+	Uniform:    "uniform",
+	SkewNormal: "skew-normal",
+	Seasonal:   "seasonal",
+}
+
+// DistributionNames returns the names of the distributions accepted by
+// ParseDistribution.
+func DistributionNames() []string {
+	return []string{distributionNames[Normal], distributionNames[Uniform], distributionNames[SkewNormal], distributionNames[Seasonal]}
+}
+
+// ParseDistribution returns the Distribution called name.
+func ParseDistribution(name string) (Distribution, error) {
+	for distribution, distributionName := range distributionNames {
+		if distributionName == name {
+			return distribution, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown distribution %q, expected one of %v", name, DistributionNames())
+}
+
+func (d Distribution) String() string {
+	if name, ok := distributionNames[d]; ok {
+		return name
+	}
+	return fmt.Sprintf("Distribution(%d)", int(d))
+}
+
+// MinTemperature and MaxTemperature are the range of the temperatures of the
+// challenge, every generated temperature is clamped to it.
+const (
+	MinTemperature = -99.9
+	MaxTemperature = 99.9
+)
+
+// Model describes how the temperatures of a station are drawn around its
+// mean temperature.
+type Model struct {
+	Distribution Distribution
+	// StdDev is the standard deviation of the temperatures, without the
+	// sinusoid of the Seasonal distribution.
+	StdDev float64
+	// Skew is the shape of the SkewNormal distribution, 0 is a normal
+	// distribution.
+	Skew float64
+	// Amplitude and Cycles are the amplitude and the number of periods over
+	// the file of the sinusoid of the Seasonal distribution. Cycles defaults
+	// to 1, one simulated year per file.
+	Amplitude float64
+	Cycles    float64
+	// Min and Max clamp the temperatures of the station when Clamp is set,
+	// like the range of a sensor. Otherwise only the range of the challenge
+	// applies.
+	Clamp    bool
+	Min, Max float64
+}
+
+// sample draws a temperature around mean at the time t, from 0 at the first
+// row of the file to 1 at the last one.
+func (m *Model) sample(randomGenerator *rand.Rand, mean, t float64) float64 {
+	var value float64
+	switch m.Distribution {
+	case Uniform:
+		value = mean + (2*randomGenerator.Float64()-1)*math.Sqrt(3)*m.StdDev
+	case SkewNormal:
+		value = mean + skewNormal(randomGenerator, m.Skew)*m.StdDev
+	case Seasonal:
+		cycles := m.Cycles
+		if cycles == 0 {
+			cycles = 1
+		}
+		value = mean + m.Amplitude*math.Sin(2*math.Pi*cycles*t) + randomGenerator.NormFloat64()*m.StdDev
+	default:
+		value = mean + randomGenerator.NormFloat64()*m.StdDev
+	}
+	if m.Clamp {
+		value = clamp(value, m.Min, m.Max)
+	}
+	return value
+}
+
+// skewNormal draws a skew-normal number of shape alpha, standardized to a
+// mean of 0 and a standard deviation of 1.
+func skewNormal(randomGenerator *rand.Rand, alpha float64) float64 {
+	delta := alpha / math.Sqrt(1+alpha*alpha)
+	u0 := randomGenerator.NormFloat64()
+	v := randomGenerator.NormFloat64()
+	u1 := delta*u0 + math.Sqrt(1-delta*delta)*v
+	if u0 < 0 {
+		u1 = -u1
+	}
+	mean := delta * math.Sqrt(2/math.Pi)
+	return (u1 - mean) / math.Sqrt(1-mean*mean)
+}
+
+func clamp(value, min, max float64) float64 {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
+
+// validate checks that the parameters of the model are finite and
+// consistent.
+func (m *Model) validate() error {
+	if _, ok := distributionNames[m.Distribution]; !ok {
+		return fmt.Errorf("unknown distribution %v", m.Distribution)
+	}
+	if !(m.StdDev >= 0) || math.IsInf(m.StdDev, 0) {
+		return fmt.Errorf("invalid stddev %v", m.StdDev)
+	}
+	for _, param := range []struct {
+		name  string
+		value float64
+	}{{"skew", m.Skew}, {"amplitude", m.Amplitude}, {"cycles", m.Cycles}, {"min", m.Min}, {"max", m.Max}} {
+		if math.IsNaN(param.value) || math.IsInf(param.value, 0) {
+			return fmt.Errorf("invalid %s %v", param.name, param.value)
+		}
+	}
+	if m.Cycles < 0 {
+		return fmt.Errorf("invalid cycles %v", m.Cycles)
+	}
+	if m.Clamp && m.Min > m.Max {
+		return fmt.Errorf("min %v is greater than max %v", m.Min, m.Max)
+	}
+	return nil
+}
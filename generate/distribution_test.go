@@ -0,0 +1,83 @@
+package generate
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"1brc/round"
+)
+
+// moments returns the mean, standard deviation and skewness of values.
+func moments(values []float64) (mean, stdDev, skewness float64) {
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	var m2, m3 float64
+	for _, v := range values {
+		d := v - mean
+		m2 += d * d
+		m3 += d * d * d
+	}
+	m2 /= float64(len(values))
+	m3 /= float64(len(values))
+	return mean, math.Sqrt(m2), m3 / math.Pow(m2, 1.5)
+}
+
+func TestDistributionMoments(t *testing.T) {
+	tests := []struct {
+		model        Model
+		skewPositive bool
+	}{
+		{model: Model{Distribution: Normal, StdDev: 5}},
+		{model: Model{Distribution: Uniform, StdDev: 5}},
+		{model: Model{Distribution: SkewNormal, StdDev: 5, Skew: 5}, skewPositive: true},
+	}
+	for _, test := range tests {
+		randomGenerator := rand.New(rand.NewSource(1))
+		values := make([]float64, 200000)
+		for i := range values {
+			values[i] = test.model.sample(randomGenerator, 12, 0)
+		}
+		mean, stdDev, skewness := moments(values)
+		if math.Abs(mean-12) > 0.1 || math.Abs(stdDev-5) > 0.1 {
+			t.Errorf("%v: mean %.3f and stddev %.3f, expected 12 and 5", test.model.Distribution, mean, stdDev)
+		}
+		if test.skewPositive != (skewness > 0.3) {
+			t.Errorf("%v: skewness %.3f", test.model.Distribution, skewness)
+		}
+	}
+}
+
+func TestSeasonal(t *testing.T) {
+	model := Model{Distribution: Seasonal, Amplitude: 20, Cycles: 2}
+	randomGenerator := rand.New(rand.NewSource(1))
+	for _, test := range []struct{ t, expected float64 }{{0, 10}, {0.125, 30}, {0.375, -10}, {0.625, 30}} {
+		if value := model.sample(randomGenerator, 10, test.t); math.Abs(value-test.expected) > 1e-9 {
+			t.Errorf("sample at %v = %v, expected %v", test.t, value, test.expected)
+		}
+	}
+}
+
+func TestTemperatureClamped(t *testing.T) {
+	randomGenerator := rand.New(rand.NewSource(1))
+	hot := NewStationModel("hot", 95, Model{StdDev: 20})
+	sensor := NewStationModel("sensor", 0, Model{Distribution: Uniform, StdDev: 50, Clamp: true, Min: -10.05, Max: 10})
+	for i := 0; i < 10000; i++ {
+		if temperature := hot.temperature(randomGenerator, round.HalfUp, 0); temperature > MaxTemperature {
+			t.Fatalf("temperature %v above %v", temperature, MaxTemperature)
+		}
+		if temperature := sensor.temperature(randomGenerator, round.HalfUp, 0); temperature < -10 || temperature > 10 {
+			t.Fatalf("temperature %v out of [-10, 10]", temperature)
+		}
+	}
+
+	// zero is a valid range, not the absence of one
+	frozen := NewStationModel("frozen", 5, Model{StdDev: 10, Clamp: true})
+	for i := 0; i < 100; i++ {
+		if temperature := frozen.temperature(randomGenerator, round.HalfUp, 0); temperature != 0 {
+			t.Fatalf("temperature %v, expected 0", temperature)
+		}
+	}
+}
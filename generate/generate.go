@@ -0,0 +1,306 @@
+// Package generate writes measurement files in the format expected by the
+// one billion rows challenge: one "station;temperature" row per line.
+package generate
+
+import (
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"1brc/round"
+)
+
+// Station is a weather station with the mean temperature around which its
+// measurements are generated.
+type Station struct {
+	id              string
+	meanTemperature float64
+	model           Model
+	weight          float64
+}
+
+// DefaultStdDev is the standard deviation of the temperatures of a station
+// created with NewStation.
+const DefaultStdDev = 10
+
+// NewStation returns a station named id.
+func NewStation(id string, meanTemperature float64) *Station {
+	// pseudorandom normally distributed number with normal distribution mean (meanTemperature) and standard deviation (10)
+	return NewStationStdDev(id, meanTemperature, DefaultStdDev)
+}
+
+// NewStationStdDev returns a station named id whose temperatures have the
+// standard deviation stdDev.
+func NewStationStdDev(id string, meanTemperature, stdDev float64) *Station {
+	return NewStationModel(id, meanTemperature, Model{StdDev: stdDev})
+}
+
+// NewStationModel returns a station named id whose temperatures are drawn
+// from model.
+func NewStationModel(id string, meanTemperature float64, model Model) *Station {
+	return &Station{id: id, meanTemperature: meanTemperature, model: model, weight: 1}
+}
+
+// Name returns the name of the station.
+func (s *Station) Name() string {
+	return s.id
+}
+
+// MeanTemperature returns the mean of the generated temperatures.
+func (s *Station) MeanTemperature() float64 {
+	return s.meanTemperature
+}
+
+// StdDev returns the standard deviation of the generated temperatures.
+func (s *Station) StdDev() float64 {
+	return s.model.StdDev
+}
+
+// Model returns the model the temperatures are drawn from.
+func (s *Station) Model() Model {
+	return s.model
+}
+
+// Weight returns the relative frequency of the station with SelectWeighted.
+// Defaults to 1.
+func (s *Station) Weight() float64 {
+	return s.weight
+}
+
+// SetWeight sets the relative frequency of the station with SelectWeighted.
+func (s *Station) SetWeight(weight float64) {
+	s.weight = weight
+}
+
+// temperature draws a temperature at the time t of the simulated time axis,
+// from 0 at the first row to 1 at the last one.
+func (s *Station) temperature(randomGenerator *rand.Rand, rounding round.Mode, t float64) float64 {
+	randFloat := s.model.sample(randomGenerator, s.meanTemperature, t)
+	roundedFloat := rounding.Round(randFloat, 1)
+	return clamp(roundedFloat, MinTemperature, MaxTemperature)
+}
+
+// Stats reports what MeasurementFile wrote.
+type Stats struct {
+	Rows  int64
+	Bytes int64
+}
+
+// batchRows is the number of rows a worker renders before writing them, a
+// variable so that the tests can use many small batches.
+var batchRows = 1 << 16
+
+// progressRows is the number of rows between two calls of Options.Progress,
+// a variable like batchRows for the tests.
+var progressRows int64 = 50000000
+
+// MeasurementFile writes exactly numberOfRows random measurements of
+// opts.Stations to filename.
+// The rows are split into batches handed to the workers in turn. Every worker
+// renders its batch into its own buffer, then waits for the offset of the
+// batch from the worker of the previous batch, which passes it on as soon as
+// it rendered its own batch. The worker passes the offset following its batch
+// on to the next worker and writes its batch there with WriteAt. Rendering
+// and writing are concurrent, only the offsets are handed off in the order of
+// the batches, so the order of the rows is always the same. With a non zero
+// opts.Seed the file is the same for the same seed, number of rows and number
+// of workers.
+func MeasurementFile(filename string, numberOfRows int, opts Options) (Stats, error) {
+	opts = opts.withDefaults()
+	seed := opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	if len(opts.Stations) == 0 {
+		return Stats{}, errors.New("no stations to generate measurements for")
+	}
+	selection, err := selectionTable(opts)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	// Create the output file
+	file, err := os.Create(filename)
+	if err != nil {
+		return Stats{}, err
+	}
+	defer file.Close()
+
+	stats, err := writeRows(file, numberOfRows, seed, selection, opts)
+	if err != nil {
+		return Stats{}, err
+	}
+	return stats, file.Close()
+}
+
+// writeRows writes the rows of MeasurementFile to file. It returns the first
+// error of the workers once all of them stopped.
+func writeRows(file io.WriterAt, numberOfRows int, seed int64, selection *aliasTable, opts Options) (Stats, error) {
+	maxGoRoutines := opts.Workers
+	batches := (numberOfRows + batchRows - 1) / batchRows
+	workerStats := make([]Stats, maxGoRoutines)
+
+	// offsetChs[i] receives the offset of the next batch of worker i from the
+	// worker of the previous batch, once that batch is rendered
+	offsetChs := make([]chan int64, maxGoRoutines)
+	for i := range offsetChs {
+		offsetChs[i] = make(chan int64, 1)
+	}
+	offsetChs[0] <- 0
+
+	var wg sync.WaitGroup
+	errCh := make(chan error, maxGoRoutines)
+	// done is closed on the first error: the offsets of the batches of the
+	// worker that failed will never come
+	done := make(chan struct{})
+	var written atomic.Int64
+
+	for i := 0; i < maxGoRoutines; i++ {
+		w := &worker{
+			file:            file,
+			index:           i,
+			workers:         maxGoRoutines,
+			batches:         batches,
+			numberOfRows:    numberOfRows,
+			stations:        opts.Stations,
+			selection:       selection,
+			randomGenerator: rand.New(rand.NewSource(workerSeed(seed, i))),
+			rounding:        opts.Rounding,
+			offsetCh:        offsetChs[i],
+			nextOffsetCh:    offsetChs[(i+1)%maxGoRoutines],
+			done:            done,
+			stats:           &workerStats[i],
+			written:         &written,
+			progress:        opts.Progress,
+		}
+		wg.Add(1)
+		go w.generateData(&wg, errCh)
+	}
+
+	// Close the error channel when all workers are done
+	go func() {
+		wg.Wait()
+		close(errCh)
+	}()
+
+	// Collect the errors until all the workers stopped
+	var firstErr error
+	for err := range errCh {
+		if firstErr == nil {
+			firstErr = err
+			close(done)
+		}
+	}
+	if firstErr != nil {
+		return Stats{}, firstErr
+	}
+
+	var stats Stats
+	for _, s := range workerStats {
+		stats.Rows += s.Rows
+		stats.Bytes += s.Bytes
+	}
+	return stats, nil
+}
+
+// workerSeed derives the seed of the random generator of a worker from the
+// seed of the run with the splitmix64 finalizer, so that the workers get
+// unrelated streams.
+func workerSeed(seed int64, worker int) int64 {
+	z := uint64(seed) + uint64(worker+1)*0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return int64(z ^ (z >> 31))
+}
+
+// worker renders the batches index, index+workers, index+2*workers...
+type worker struct {
+	file         io.WriterAt
+	index        int
+	workers      int
+	batches      int
+	numberOfRows int
+	stations     []*Station
+	// selection picks the stations, uniformly when nil
+	selection       *aliasTable
+	randomGenerator *rand.Rand
+	rounding        round.Mode
+	offsetCh        <-chan int64
+	nextOffsetCh    chan<- int64
+	// done stops the workers once one of them could not write
+	done  <-chan struct{}
+	stats *Stats
+	// written counts the rows of all the workers for progress
+	written  *atomic.Int64
+	progress func(rows int64)
+}
+
+func (w *worker) generateData(wg *sync.WaitGroup, errCh chan error) {
+	defer wg.Done()
+	var buffer []byte
+
+	for batch := w.index; batch < w.batches; batch += w.workers {
+		rows := batchRows
+		if batch == w.batches-1 {
+			rows = w.numberOfRows - batch*batchRows
+		}
+
+		buffer = buffer[:0]
+		firstRow := batch * batchRows
+		for i := 0; i < rows; i++ {
+			var randElement int
+			if w.selection != nil {
+				randElement = w.selection.sample(w.randomGenerator)
+			} else {
+				randElement = w.randomGenerator.Intn(len(w.stations))
+			}
+			station := w.stations[randElement]
+			t := float64(firstRow+i) / float64(w.numberOfRows)
+			buffer = appendRow(buffer, station.id, station.temperature(w.randomGenerator, w.rounding, t))
+		}
+
+		var offset int64
+		select {
+		case offset = <-w.offsetCh:
+		case <-w.done:
+			return
+		}
+		w.nextOffsetCh <- offset + int64(len(buffer))
+		if _, err := w.file.WriteAt(buffer, offset); err != nil {
+			errCh <- err
+			return
+		}
+
+		w.stats.Rows += int64(rows)
+		w.stats.Bytes += int64(len(buffer))
+		if w.progress != nil {
+			written := w.written.Add(int64(rows))
+			if written/progressRows != (written-int64(rows))/progressRows {
+				w.progress(written)
+			}
+		}
+	}
+}
+
+// appendRow appends a "name;temperature\n" row to buffer without allocating.
+// The temperature always has exactly one decimal digit.
+func appendRow(buffer []byte, name string, temperature float64) []byte {
+	buffer = append(buffer, name...)
+	buffer = append(buffer, ';')
+	// the temperature is already rounded to one decimal digit
+	tenths := int64(math.Round(temperature * 10))
+	if tenths < 0 {
+		buffer = append(buffer, '-')
+		tenths = -tenths
+	}
+	buffer = strconv.AppendInt(buffer, tenths/10, 10)
+	buffer = append(buffer, '.', byte('0'+tenths%10), '\n')
+	return buffer
+}
@@ -0,0 +1,144 @@
+package generate
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func generateFile(t *testing.T, rows int, opts Options) []byte {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "measurements.txt")
+	stats, err := MeasurementFile(path, rows, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := bytes.Count(data, []byte("\n")); lines != rows || stats.Rows != int64(rows) {
+		t.Errorf("wrote %d lines, reported %d rows, expected %d", lines, stats.Rows, rows)
+	}
+	if stats.Bytes != int64(len(data)) {
+		t.Errorf("wrote %d bytes, reported %d", len(data), stats.Bytes)
+	}
+	return data
+}
+
+func TestMeasurementFileSeed(t *testing.T) {
+	opts := Options{Workers: 4, Seed: 42}
+	first := generateFile(t, 10000, opts)
+	second := generateFile(t, 10000, opts)
+	if !bytes.Equal(first, second) {
+		t.Error("the same seed generated different files")
+	}
+
+	opts.Seed = 43
+	if other := generateFile(t, 10000, opts); bytes.Equal(first, other) {
+		t.Error("different seeds generated the same file")
+	}
+}
+
+// smallBatches makes the workers render batches of rows rows for the test.
+func smallBatches(t *testing.T, rows int) {
+	previous := batchRows
+	batchRows = rows
+	t.Cleanup(func() { batchRows = previous })
+}
+
+func TestMeasurementFileSmallBatches(t *testing.T) {
+	smallBatches(t, 7)
+	for _, workers := range []int{1, 2, 5, 16} {
+		opts := Options{Workers: workers, Seed: 42}
+		first := generateFile(t, 10000, opts)
+		second := generateFile(t, 10000, opts)
+		if !bytes.Equal(first, second) {
+			t.Errorf("the same seed generated different files with %d workers", workers)
+		}
+	}
+}
+
+func TestMeasurementFileProgress(t *testing.T) {
+	smallBatches(t, 7)
+	previous := progressRows
+	progressRows = 1000
+	t.Cleanup(func() { progressRows = previous })
+
+	var mu sync.Mutex
+	var calls []int64
+	generateFile(t, 10000, Options{Workers: 4, Progress: func(rows int64) {
+		mu.Lock()
+		calls = append(calls, rows)
+		mu.Unlock()
+	}})
+	// every multiple of 1000 rows is passed once, by the total of all the workers
+	sort.Slice(calls, func(i, j int) bool { return calls[i] < calls[j] })
+	if len(calls) != 10 || calls[9] != 10000 {
+		t.Fatalf("progress called with %v", calls)
+	}
+	for i, rows := range calls {
+		if rows < int64(i+1)*1000 || rows >= int64(i+1)*1000+7 {
+			t.Errorf("progress call %d with %d rows", i, rows)
+		}
+	}
+}
+
+// failingWriter fails every write after the first ones.
+type failingWriter struct {
+	writes atomic.Int64
+	after  int64
+}
+
+var errWrite = errors.New("write failed")
+
+func (f *failingWriter) WriteAt(p []byte, offset int64) (int, error) {
+	if f.writes.Add(1) > f.after {
+		return 0, errWrite
+	}
+	return len(p), nil
+}
+
+func TestWriteRowsError(t *testing.T) {
+	smallBatches(t, 3)
+	opts := Options{Workers: 8, Seed: 1}.withDefaults()
+	for _, after := range []int64{0, 1, 5, 100} {
+		_, err := writeRows(&failingWriter{after: after}, 10000, opts.Seed, nil, opts)
+		if !errors.Is(err, errWrite) {
+			t.Errorf("writeRows() failing after %d writes = %v, expected %v", after, err, errWrite)
+		}
+	}
+}
+
+func TestMeasurementFileRows(t *testing.T) {
+	for _, rows := range []int{0, 1, 5, 999, 1001} {
+		for _, workers := range []int{1, 3, 6} {
+			generateFile(t, rows, Options{Workers: workers})
+			generateFile(t, rows, Options{Workers: workers, Seed: 1})
+		}
+	}
+}
+
+func TestAppendRow(t *testing.T) {
+	tests := []struct {
+		temperature float64
+		expected    string
+	}{
+		{0, "Abha;0.0\n"},
+		{7, "Abha;7.0\n"},
+		{-0.1, "Abha;-0.1\n"},
+		{12.3, "Abha;12.3\n"},
+		{-99.9, "Abha;-99.9\n"},
+		{99.9, "Abha;99.9\n"},
+	}
+	for _, test := range tests {
+		if got := string(appendRow(nil, "Abha", test.temperature)); got != test.expected {
+			t.Errorf("appendRow(%v) = %q, expected %q", test.temperature, got, test.expected)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package generate
+
+import (
+	"runtime"
+
+	"1brc/round"
+)
+
+// Options configures how a measurements file is generated.
+// The zero value uses the defaults.
+type Options struct {
+	// Workers is the number of goroutines writing rows. Defaults to GOMAXPROCS.
+	Workers int
+	// Rounding rounds the generated temperatures to one decimal digit.
+	// Defaults to round.HalfUp.
+	Rounding round.Mode
+	// Seed makes the generated file reproducible: every worker derives its
+	// random stream from Seed and its index. Zero seeds from the clock.
+	Seed int64
+	// Stations are the stations the measurements are drawn from.
+	// Defaults to the built-in Stations.
+	Stations []*Station
+	// Selection is how the station of every row is picked. Defaults to
+	// SelectUniform.
+	Selection Selection
+	// ZipfExponent is the exponent of SelectZipf. Defaults to
+	// DefaultZipfExponent.
+	ZipfExponent float64
+	// Progress, when not nil, is called with the total number of rows
+	// written every 50 million rows. It is called from the workers,
+	// possibly concurrently.
+	Progress func(rows int64)
+}
+
+func (o Options) withDefaults() Options {
+	if o.Workers < 1 {
+		o.Workers = runtime.GOMAXPROCS(0)
+	}
+	if o.Stations == nil {
+		o.Stations = Stations()
+	}
+	if o.ZipfExponent == 0 {
+		o.ZipfExponent = DefaultZipfExponent
+	}
+	return o
+}
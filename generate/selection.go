@@ -0,0 +1,145 @@
+package generate
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Selection is how the station of every row is picked.
+type Selection int
+
+const (
+	// SelectUniform picks every station with the same probability.
+	SelectUniform Selection = iota
+	// SelectWeighted picks the stations in proportion to their weights.
+	SelectWeighted
+	// SelectZipf picks the station of rank k, its position in the list
+	// starting at 1, in proportion to 1/k^s with s the Zipf exponent, so that
+	// the first stations are hot keys.
+	SelectZipf
+)
+
+var selectionNames = map[Selection]string{
+	SelectUniform:  "uniform",
+	SelectWeighted: "weighted",
+	SelectZipf:     "zipf",
+}
+
+// SelectionNames returns the names of the selections accepted by
+// ParseSelection.
+func SelectionNames() []string {
+	return []string{selectionNames[SelectUniform], selectionNames[SelectWeighted], selectionNames[SelectZipf]}
+}
+
+// ParseSelection returns the Selection called name.
+func ParseSelection(name string) (Selection, error) {
+	for selection, selectionName := range selectionNames {
+		if selectionName == name {
+			return selection, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown selection %q, expected one of %v", name, SelectionNames())
+}
+
+func (s Selection) String() string {
+	if name, ok := selectionNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("Selection(%d)", int(s))
+}
+
+// DefaultZipfExponent is the Zipf exponent used when Options.ZipfExponent is
+// zero.
+const DefaultZipfExponent = 1
+
+// ZipfWeights returns the weights 1/k^exponent of the ranks 1 to n.
+func ZipfWeights(n int, exponent float64) []float64 {
+	weights := make([]float64, n)
+	for i := range weights {
+		weights[i] = math.Pow(float64(i+1), -exponent)
+	}
+	return weights
+}
+
+// aliasTable samples indexes in proportion to their weights in O(1) with
+// Vose's alias method: index i is picked with the probability prob[i] of its
+// column, and alias[i] otherwise.
+type aliasTable struct {
+	prob  []float64
+	alias []int
+}
+
+func newAliasTable(weights []float64) (*aliasTable, error) {
+	var sum float64
+	for i, weight := range weights {
+		if !(weight >= 0) || math.IsInf(weight, 0) {
+			return nil, fmt.Errorf("station %d: invalid weight %v", i+1, weight)
+		}
+		sum += weight
+	}
+	if !(sum > 0) || math.IsInf(sum, 0) {
+		return nil, errors.New("the sum of the weights must be positive and finite")
+	}
+
+	n := len(weights)
+	table := &aliasTable{prob: make([]float64, n), alias: make([]int, n)}
+	scaled := make([]float64, n)
+	var small, large []int
+	for i, weight := range weights {
+		scaled[i] = weight * float64(n) / sum
+		if scaled[i] < 1 {
+			small = append(small, i)
+		} else {
+			large = append(large, i)
+		}
+	}
+	for len(small) > 0 && len(large) > 0 {
+		s, l := small[len(small)-1], large[len(large)-1]
+		small = small[:len(small)-1]
+		table.prob[s] = scaled[s]
+		table.alias[s] = l
+		scaled[l] -= 1 - scaled[s]
+		if scaled[l] < 1 {
+			large = large[:len(large)-1]
+			small = append(small, l)
+		}
+	}
+	// what is left is 1 up to rounding errors
+	for _, i := range large {
+		table.prob[i] = 1
+	}
+	for _, i := range small {
+		table.prob[i] = 1
+	}
+	return table, nil
+}
+
+func (t *aliasTable) sample(randomGenerator *rand.Rand) int {
+	i := randomGenerator.Intn(len(t.prob))
+	if randomGenerator.Float64() < t.prob[i] {
+		return i
+	}
+	return t.alias[i]
+}
+
+// selectionTable returns the alias table of the selection of opts, nil for
+// SelectUniform.
+func selectionTable(opts Options) (*aliasTable, error) {
+	var weights []float64
+	switch opts.Selection {
+	case SelectUniform:
+		return nil, nil
+	case SelectWeighted:
+		weights = make([]float64, len(opts.Stations))
+		for i, station := range opts.Stations {
+			weights[i] = station.weight
+		}
+	case SelectZipf:
+		weights = ZipfWeights(len(opts.Stations), opts.ZipfExponent)
+	default:
+		return nil, fmt.Errorf("unknown selection %v", opts.Selection)
+	}
+	return newAliasTable(weights)
+}
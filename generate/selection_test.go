@@ -0,0 +1,66 @@
+package generate
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestAliasTable(t *testing.T) {
+	weights := []float64{5, 0, 1, 3, 0.5, 0.5}
+	table, err := newAliasTable(weights)
+	if err != nil {
+		t.Fatal(err)
+	}
+	randomGenerator := rand.New(rand.NewSource(1))
+	const samples = 1000000
+	counts := make([]int, len(weights))
+	for i := 0; i < samples; i++ {
+		counts[table.sample(randomGenerator)]++
+	}
+	for i, weight := range weights {
+		expected := weight / 10
+		if got := float64(counts[i]) / samples; math.Abs(got-expected) > 0.003 {
+			t.Errorf("index %d picked with probability %.4f, expected %.4f", i, got, expected)
+		}
+	}
+}
+
+func TestAliasTableErrors(t *testing.T) {
+	for _, weights := range [][]float64{{0, 0}, {1, -1}, {1, math.NaN()}, {math.Inf(1)}} {
+		if _, err := newAliasTable(weights); err == nil {
+			t.Errorf("newAliasTable(%v) should fail", weights)
+		}
+	}
+}
+
+func TestMeasurementFileSelection(t *testing.T) {
+	stations := []*Station{NewStation("a", 0), NewStation("b", 0), NewStation("c", 0)}
+	stations[2].SetWeight(0)
+	data := generateFile(t, 30000, Options{Stations: stations, Selection: SelectWeighted, Seed: 1})
+	if count := bytes.Count(data, []byte("c;")); count != 0 {
+		t.Errorf("station with weight 0 picked %d times", count)
+	}
+
+	data = generateFile(t, 30000, Options{Stations: stations, Selection: SelectZipf, ZipfExponent: 2, Seed: 1})
+	// 1, 1/4 and 1/9 of 1.36
+	counts := []int{bytes.Count(data, []byte("a;")), bytes.Count(data, []byte("b;")), bytes.Count(data, []byte("c;"))}
+	if counts[0] < 21000 || counts[1] < 5000 || counts[1] > 6000 || counts[2] < 2000 || counts[2] > 2900 {
+		t.Errorf("zipf counts %v", counts)
+	}
+}
+
+func TestReadCatalogWeight(t *testing.T) {
+	stations, err := ReadCatalogCSV(strings.NewReader("name,mean,weight\nAbha,18.0,40\nAden,29.1,\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stations[0].Weight() != 40 || stations[1].Weight() != 1 {
+		t.Errorf("weights %v and %v, expected 40 and 1", stations[0].Weight(), stations[1].Weight())
+	}
+	if _, err := ReadCatalogCSV(strings.NewReader("name,mean,weight\nAbha,18.0,-1\n")); err == nil {
+		t.Error("negative weight should fail")
+	}
+}
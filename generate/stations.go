@@ -1,113 +1,8 @@
-package main
+package generate
 
-import (
-	"bufio"
-	"fmt"
-	"math/rand"
-	"os"
-	"runtime"
-	"sync"
-	"time"
-)
-
-var mutexFile = &sync.RWMutex{}
-
-type Station struct {
-	id              string
-	meanTemperature float64
-}
-
-func NewStation(id string, meanTemperature float64) *Station {
-	// pseudorandom normally distributed number with normal distribution mean (meanTemperature) and standard deviation (10)
-	return &Station{id: id, meanTemperature: meanTemperature}
-}
-
-func (s *Station) temperature(randomGenerator *rand.Rand) float64 {
-	randFloat := randomGenerator.NormFloat64()*10 + s.meanTemperature
-	roundedFloat := roundFloat(randFloat, 1)
-	return roundedFloat
-}
-
-func generateMeasurementFile(numberOfRows int) error {
-
-	filename := "measurements.txt"
-	stations := getListOfStations()
-
-	// Create the output file
-	os.Create(filename)
-	file, err := os.Create(filename)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	// Calculate the number of rows to be written by each worker
-	maxGoRoutines := runtime.GOMAXPROCS(0)
-	rowsPerTask := numberOfRows / maxGoRoutines
-
-	var wg sync.WaitGroup
-	errCh := make(chan error, maxGoRoutines)
-
-	for i := 0; i < maxGoRoutines; i++ {
-		wg.Add(1)
-		go generateData(file, &wg, rowsPerTask, stations, errCh)
-	}
-
-	// Close the error channel when all workers are done
-	go func() {
-		wg.Wait()
-		close(errCh)
-	}()
-
-	// Collect and handle errors
-	for err := range errCh {
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
-func generateData(
-	file *os.File,
-	wg *sync.WaitGroup,
-	rowsPerTask int,
-	stations []*Station,
-	errCh chan error,
-) {
-	defer wg.Done()
-	// each goroutine should have its own instance of random generator because rand.Rand is not safe concurrently. It throws an out of index error.
-	randomGenerator := rand.New(rand.NewSource(time.Now().UnixNano()))
-	startTime := time.Now()
-	bufSize := 65536
-	writer := bufio.NewWriterSize(file, bufSize)
-
-	for i := 0; i < rowsPerTask; i++ {
-		if i > 0 && i%50000000 == 0 {
-			fmt.Printf("Wrote %d measurements in %d \n", i, time.Since(startTime))
-		}
-		randElement := rand.Intn(len(stations))
-		station := stations[randElement]
-		data := fmt.Sprint(station.id, ";", station.temperature(randomGenerator), "\n")
-		buffered := writer.Buffered()
-		if (bufSize - buffered) < 2000 {
-			mutexFile.Lock()
-			writer.Flush()
-			mutexFile.Unlock()
-		}
-		_, err := writer.WriteString(data)
-		if err != nil {
-			errCh <- err
-			return
-		}
-	}
-	mutexFile.Lock()
-	writer.Flush()
-	mutexFile.Unlock()
-}
-
-func getListOfStations() []*Station {
+// Stations returns the built-in list of weather stations used by the
+// original challenge.
+func Stations() []*Station {
 
 	stations := []*Station{
 		NewStation("Abha", 18.0),
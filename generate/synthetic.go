@@ -0,0 +1,97 @@
+package generate
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+	"unicode/utf8"
+
+	"1brc/round"
+)
+
+// SyntheticOptions configures the stations created by SyntheticStations.
+type SyntheticOptions struct {
+	// Count is the number of stations, 10000 for the 10K variant of the challenge.
+	Count int
+	// MinNameLength and MaxNameLength bound the length of the names in bytes.
+	// The lengths are uniformly distributed between them.
+	// They default to 1 and 100, the limits of the challenge.
+	MinNameLength, MaxNameLength int
+	// MultiByteRatio is the probability of every character of a name to be a
+	// multi-byte UTF-8 character instead of an ASCII letter.
+	MultiByteRatio float64
+	// MinMean and MaxMean bound the uniformly distributed mean temperatures.
+	// They default to -30 and 40 when both are zero.
+	MinMean, MaxMean float64
+	// Seed makes the stations reproducible. Zero seeds from the clock.
+	Seed int64
+}
+
+var asciiLetters = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
+
+// multiByteRunes are encoded in 2, 3 and 4 bytes.
+var multiByteRunes = []rune("éüßøñçÅŁŻЖжЯΩλ東京都市場€ह😀🌡🌍")
+
+// SyntheticStations creates opts.Count stations with unique random names
+// and random means, to stress the processing with many distinct and long
+// station names.
+func SyntheticStations(opts SyntheticOptions) ([]*Station, error) {
+	if opts.MinNameLength < 1 {
+		opts.MinNameLength = 1
+	}
+	if opts.MaxNameLength < 1 {
+		opts.MaxNameLength = 100
+	}
+	if opts.MaxNameLength < opts.MinNameLength {
+		return nil, fmt.Errorf("max name length %d is lower than min name length %d", opts.MaxNameLength, opts.MinNameLength)
+	}
+	if opts.MinMean == 0 && opts.MaxMean == 0 {
+		opts.MinMean, opts.MaxMean = -30, 40
+	}
+	if opts.Count < 1 {
+		return nil, errors.New("the number of synthetic stations must be >= 1")
+	}
+	seed := opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	randomGenerator := rand.New(rand.NewSource(seed))
+
+	stations := make([]*Station, 0, opts.Count)
+	names := make(map[string]bool, opts.Count)
+	// short names run out quickly, give up instead of looping forever
+	attempts := 0
+	for len(stations) < opts.Count {
+		attempts++
+		if attempts > opts.Count*100 {
+			return nil, fmt.Errorf("could only create %d unique names of %d to %d bytes", len(stations), opts.MinNameLength, opts.MaxNameLength)
+		}
+		length := opts.MinNameLength + randomGenerator.Intn(opts.MaxNameLength-opts.MinNameLength+1)
+		name := syntheticName(randomGenerator, length, opts.MultiByteRatio)
+		if names[name] {
+			continue
+		}
+		names[name] = true
+		mean := opts.MinMean + randomGenerator.Float64()*(opts.MaxMean-opts.MinMean)
+		stations = append(stations, NewStation(name, round.HalfUp.Round(mean, 1)))
+	}
+	return stations, nil
+}
+
+// syntheticName returns a random name of exactly length bytes.
+func syntheticName(randomGenerator *rand.Rand, length int, multiByteRatio float64) string {
+	name := make([]byte, 0, length)
+	for len(name) < length {
+		r := asciiLetters[randomGenerator.Intn(len(asciiLetters))]
+		if randomGenerator.Float64() < multiByteRatio {
+			multiByte := multiByteRunes[randomGenerator.Intn(len(multiByteRunes))]
+			// fall back to an ASCII letter when the rune does not fit
+			if len(name)+utf8.RuneLen(multiByte) <= length {
+				r = multiByte
+			}
+		}
+		name = utf8.AppendRune(name, r)
+	}
+	return string(name)
+}
@@ -0,0 +1,64 @@
+package generate
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSyntheticStations(t *testing.T) {
+	opts := SyntheticOptions{Count: 10000, MultiByteRatio: 0.3, Seed: 1}
+	stations, err := SyntheticStations(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateStations(stations); err != nil {
+		t.Fatal(err)
+	}
+	if len(stations) != opts.Count {
+		t.Fatalf("got %d stations, expected %d", len(stations), opts.Count)
+	}
+
+	var multiByte, shortest, longest int
+	shortest = 100
+	for _, station := range stations {
+		name := station.Name()
+		if !utf8.ValidString(name) {
+			t.Fatalf("invalid UTF-8 name %q", name)
+		}
+		if len(name) < shortest {
+			shortest = len(name)
+		}
+		if len(name) > longest {
+			longest = len(name)
+		}
+		if utf8.RuneCountInString(name) != len(name) {
+			multiByte++
+		}
+		if station.MeanTemperature() < -30 || station.MeanTemperature() > 40 {
+			t.Errorf("mean %v of %q out of range", station.MeanTemperature(), name)
+		}
+	}
+	if shortest != 1 || longest != 100 {
+		t.Errorf("name lengths between %d and %d, expected 1 and 100", shortest, longest)
+	}
+	if multiByte == 0 {
+		t.Error("no multi-byte names")
+	}
+
+	again, err := SyntheticStations(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range stations {
+		if *stations[i] != *again[i] {
+			t.Fatalf("the same seed created different stations")
+		}
+	}
+}
+
+func TestSyntheticStationsTooMany(t *testing.T) {
+	_, err := SyntheticStations(SyntheticOptions{Count: 1000, MaxNameLength: 1, Seed: 1})
+	if err == nil {
+		t.Error("1000 unique names of 1 byte should fail")
+	}
+}
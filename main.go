@@ -1,227 +1,370 @@
 package main
 
 import (
-	"bufio"
+	"flag"
 	"fmt"
 	"io"
-	"math"
 	"os"
-	"sort"
-	"sync"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"time"
-	"unsafe"
+
+	"1brc/aggregate"
+	"1brc/generate"
+	"1brc/output"
+	"1brc/round"
 )
 
-type Measurements struct {
-	Min, Max, Sum, Count float64
+func main() {
+	os.Exit(run(os.Args[1:]))
 }
 
-func main() {
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
 
-	startTime := time.Now()
-	// err := generateMeasurementFile(1000000000)
-	// if err != nil {
-	// 	fmt.Println(err)
-	// 	panic("Error during file generation")
-	// }
-	// fmt.Printf("File generation executed in %v\n", time.Since(startTime))
-
-	filePath := "measurements.txt"
-	file, err := os.Open(filePath)
-	if err != nil {
-		fmt.Println(err)
-		panic("Error in file reading")
-	}
-	fileStats, err := os.Stat(filePath)
-	if err != nil {
-		fmt.Println(err)
-		panic("Got error during file stats retrieval")
-	}
-	fileSize := fileStats.Size()
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: 1brc <command> [flags]")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Commands:")
+	fmt.Fprintln(os.Stderr, "  generate  write a measurements file")
+	fmt.Fprintln(os.Stderr, "  process   calculate min/mean/max per station")
+	fmt.Fprintln(os.Stderr, "  bench     compare the processing strategies on a generated file")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Run '1brc <command> -h' for the flags of a command.")
+}
 
-	results, err := processFile(file, fileSize)
-	if err != nil && err != io.EOF {
-		fmt.Println(err)
-		panic("Processing failed")
+func run(args []string) int {
+	if len(args) == 0 {
+		usage()
+		return exitUsage
 	}
-	file.Close()
-
-	displayResults(results)
 
-	fmt.Printf("Processing executed in %v\n", time.Since(startTime))
-}
-
-func roundFloat(val float64, precision uint) float64 {
-	ratio := math.Pow(10, float64(precision))
-	return math.Round(val*ratio) / ratio
+	switch args[0] {
+	case "generate":
+		return runGenerate(args[1:])
+	case "process":
+		return runProcess(args[1:])
+	case "bench":
+		return runBench(args[1:])
+	case "help", "-h", "-help", "--help":
+		usage()
+		return exitOK
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+		usage()
+		return exitUsage
+	}
 }
 
-func convertBytesToFloat(bytes []byte) float64 {
-	var startIndex int
-	if bytes[0] == '-' {
-		startIndex = 1
+func runGenerate(args []string) int {
+	flags := flag.NewFlagSet("generate", flag.ContinueOnError)
+	outputPath := flags.String("output", "measurements.txt", "path of the file to write")
+	rows := flags.Int("rows", 1000000000, "number of rows to generate")
+	workers := flags.Int("workers", runtime.GOMAXPROCS(0), "number of goroutines writing rows")
+	rounding := flags.String("rounding", round.HalfUp.String(), "rounding of the temperatures: "+strings.Join(round.Names(), ", "))
+	catalog := flags.String("catalog", "", "CSV or JSON file with the stations (name, mean, optional stddev), the built-in stations if empty")
+	synthetic := flags.Int("synthetic", 0, "number of stations with random names and means to use instead of the built-in stations, 10000 for the 10K variant")
+	minNameLength := flags.Int("min-name-length", 1, "minimum length in bytes of the synthetic station names")
+	maxNameLength := flags.Int("max-name-length", 100, "maximum length in bytes of the synthetic station names")
+	multiByte := flags.Float64("multi-byte", 0.2, "probability of a character of a synthetic station name to be multi-byte UTF-8")
+	selection := flags.String("selection", generate.SelectUniform.String(), "how the station of every row is picked: "+strings.Join(generate.SelectionNames(), ", ")+"; weighted uses the weights of the catalog")
+	zipfExponent := flags.Float64("zipf-exponent", generate.DefaultZipfExponent, "exponent s of the zipf selection, the station of rank k is picked in proportion to 1/k^s")
+	seed := flags.Int64("seed", 0, "seed of the random generators, the same seed, rows and workers always produce the same file; 0 for a random file")
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if *rows < 0 || *workers < 1 {
+		fmt.Fprintln(os.Stderr, "rows must be >= 0 and workers must be >= 1")
+		return exitUsage
 	}
 
-	v := float64(bytes[len(bytes)-1]-'0') / 10 // single decimal digit
-	place := 1.0
-	for i := len(bytes) - 3; i >= startIndex; i-- { // integer part
-		v += float64(bytes[i]-'0') * place
-		place *= 10
+	roundingMode, err := round.ParseMode(*rounding)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
 	}
 
-	if startIndex == 1 {
-		v *= -1
+	selectionMode, err := generate.ParseSelection(*selection)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	if *zipfExponent <= 0 {
+		fmt.Fprintln(os.Stderr, "zipf-exponent must be > 0")
+		return exitUsage
 	}
-	return v
-}
 
-func processFile(file *os.File, fileSize int64) (map[string]*Measurements, error) {
+	if *catalog != "" && *synthetic > 0 {
+		fmt.Fprintln(os.Stderr, "catalog and synthetic are mutually exclusive")
+		return exitUsage
+	}
+	var stations []*generate.Station
+	if *catalog != "" {
+		stations, err = generate.LoadCatalog(*catalog)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid station catalog:", err)
+			return exitError
+		}
+	}
+	if *synthetic > 0 {
+		stations, err = generate.SyntheticStations(generate.SyntheticOptions{
+			Count:          *synthetic,
+			MinNameLength:  *minNameLength,
+			MaxNameLength:  *maxNameLength,
+			MultiByteRatio: *multiByte,
+			Seed:           *seed,
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Creating synthetic stations failed:", err)
+			return exitUsage
+		}
+	}
 
-	reader := bufio.NewReader(file)
-	bufferSize := 30 * 1024 * 1024
+	startTime := time.Now()
+	stats, err := generate.MeasurementFile(*outputPath, *rows, generate.Options{
+		Workers:      *workers,
+		Rounding:     roundingMode,
+		Seed:         *seed,
+		Stations:     stations,
+		Selection:    selectionMode,
+		ZipfExponent: *zipfExponent,
+		Progress: func(rows int64) {
+			fmt.Printf("Wrote %d measurements in %v \n", rows, time.Since(startTime))
+		},
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error during file generation:", err)
+		return exitError
+	}
+	fmt.Printf("Wrote %d rows (%d bytes) to %s\n", stats.Rows, stats.Bytes, *outputPath)
+	fmt.Printf("File generation executed in %v\n", time.Since(startTime))
 
-	var wg sync.WaitGroup
-	limiterCh := make(chan struct{}, 1000)
-	resultsCh := make(chan map[string]*Measurements, 100000)
-	errCh := make(chan error, 1000)
-	buffer := make([]byte, bufferSize)
+	return exitOK
+}
 
-	for {
-		n, err := io.ReadFull(reader, buffer)
-		buf := buffer[:n]
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			if err != io.ErrUnexpectedEOF {
-				fmt.Println("Wasn't able to read chunk of the file")
-				return nil, err
-			}
+func runProcess(args []string) int {
+	flags := flag.NewFlagSet("process", flag.ContinueOnError)
+	input := flags.String("input", "measurements.txt", "path of the measurements file to read, - for stdin; the arguments after the flags are also inputs, files or glob patterns processed together")
+	workers := flags.Int("workers", runtime.GOMAXPROCS(0), "number of chunks processed concurrently")
+	chunkSize := flags.Int("chunk-size", aggregate.DefaultChunkSize/(1024*1024), "size of the chunks in MB")
+	poolSize := flags.Int("pool-size", 0, "number of chunk buffers in reader mode, at least 2; 0 uses workers+1")
+	mode := flags.String("mode", "reader", "how the input is read: reader or mmap")
+	table := flags.String("table", "map", "table the stations are aggregated into: map or open")
+	strict := flags.Bool("strict", false, "fail on the first malformed line")
+	lenient := flags.Bool("lenient", false, "skip the malformed lines and print a summary of them")
+	samples := flags.Int("samples", 10, "number of skipped lines printed in lenient mode")
+	format := flags.String("format", "text", "format of the results: "+strings.Join(output.Names(), ", "))
+	outputPath := flags.String("output", "-", "path of the file the results are written to, - for stdout")
+	rounding := flags.String("rounding", round.HalfUp.String(), "rounding of the results: "+strings.Join(round.Names(), ", "))
+	compression := flags.String("compression", aggregate.CompressionAuto.String(), "compression of the input: "+strings.Join(aggregate.CompressionNames(), ", ")+"; auto detects it from the first bytes")
+	maxNameLength := flags.Int("max-name-length", aggregate.DefaultMaxNameLength, "maximum length in bytes of a station name in strict and lenient modes")
+	perFile := flags.String("per-file", "", "directory the results of every input file are also written to, as <file name>.out in the chosen format")
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
 		}
-
-		nextUntillNewLine, err := reader.ReadBytes('\n')
-		if err != io.EOF {
-			buf = append(buf, nextUntillNewLine...)
+		return exitUsage
+	}
+	inputs := []string{*input}
+	if flags.NArg() > 0 {
+		inputs = flags.Args()
+	}
+	inputs, err := aggregate.ExpandPaths(inputs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	multiple := len(inputs) > 1 || *perFile != ""
+	for _, path := range inputs {
+		if path == "-" && multiple {
+			fmt.Fprintln(os.Stderr, "stdin cannot be processed with other inputs or per-file")
+			return exitUsage
+		}
+	}
+	if *workers < 1 || *chunkSize < 1 || *maxNameLength < 1 {
+		fmt.Fprintln(os.Stderr, "workers, chunk-size and max-name-length must be >= 1")
+		return exitUsage
+	}
+	if *poolSize != 0 && *poolSize < 2 {
+		fmt.Fprintln(os.Stderr, "pool-size must be 0 or >= 2")
+		return exitUsage
+	}
+	opts := aggregate.Options{
+		Workers:       *workers,
+		ChunkSize:     *chunkSize * 1024 * 1024,
+		PoolSize:      *poolSize,
+		MaxNameLength: *maxNameLength,
+	}
+	if *strict && *lenient {
+		fmt.Fprintln(os.Stderr, "strict and lenient are mutually exclusive")
+		return exitUsage
+	}
+	var skipped aggregate.Skipped
+	if *strict {
+		opts.Mode = aggregate.ParseStrict
+	}
+	if *lenient {
+		opts.Mode = aggregate.ParseLenient
+		opts.Skipped = &skipped
+		opts.MaxSamples = *samples
+	}
+	newTable, ok := aggregate.Tables[*table]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown table %q\n", *table)
+		return exitUsage
+	}
+	opts.NewTable = newTable
+	writeResults, err := output.Lookup(*format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	roundingMode, err := round.ParseMode(*rounding)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	opts.Compression, err = aggregate.ParseCompression(*compression)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	processFile := aggregate.ProcessFile
+	switch *mode {
+	case "reader":
+	case "mmap":
+		processFile = aggregate.ProcessFileMmap
+	default:
+		fmt.Fprintf(os.Stderr, "unknown mode %q\n", *mode)
+		return exitUsage
+	}
+	if multiple && *mode != "reader" {
+		fmt.Fprintln(os.Stderr, "multiple inputs and per-file are only supported in reader mode")
+		return exitUsage
+	}
+	var perFileOutputs map[string]string
+	if *perFile != "" {
+		perFileOutputs, err = perFilePaths(*perFile, inputs)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
 		}
+		opts.PerFile = make(map[string]map[string]*aggregate.Measurements, len(inputs))
+	}
 
-		limiterCh <- struct{}{}
-		wg.Add(1)
-		go processData(&wg, buf, limiterCh, resultsCh, errCh)
+	startTime := time.Now()
+	var results map[string]*aggregate.Measurements
+	if multiple {
+		results, err = aggregate.ProcessFiles(inputs, opts)
+	} else {
+		results, err = processInput(inputs[0], processFile, opts)
+	}
+	if err != nil && err != io.EOF {
+		fmt.Fprintln(os.Stderr, "Processing failed:", err)
+		return exitError
 	}
 
-	go func() {
-		wg.Wait()
-		close(limiterCh)
-		close(resultsCh)
-		close(errCh)
-	}()
+	// Only the text format is followed by the summary, so that the other
+	// formats can be parsed or diffed
+	status := os.Stdout
+	if *outputPath != "-" || *format != "text" {
+		status = os.Stderr
+	}
+	if err := writeOutput(*outputPath, writeResults, results, roundingMode); err != nil {
+		fmt.Fprintln(os.Stderr, "Writing results failed:", err)
+		return exitError
+	}
+	for path, output := range perFileOutputs {
+		if err := writeOutput(output, writeResults, opts.PerFile[path], roundingMode); err != nil {
+			fmt.Fprintln(os.Stderr, "Writing results failed:", err)
+			return exitError
+		}
+	}
+	if *lenient {
+		displaySkipped(status, &skipped)
+	}
 
-	result := aggregateMaps(resultsCh)
+	fmt.Fprintf(status, "Processing executed in %v\n", time.Since(startTime))
+	if rss, ok := peakRSS(); ok {
+		fmt.Fprintf(status, "Peak RSS %.1f MB\n", float64(rss)/(1024*1024))
+	}
+	return exitOK
+}
 
-	// Collect and handle errors
-	for err := range errCh {
+// processInput processes the file at path, or stdin if path is "-".
+func processInput(path string, processFile func(*os.File, int64, aggregate.Options) (map[string]*aggregate.Measurements, error), opts aggregate.Options) (map[string]*aggregate.Measurements, error) {
+	// stdin has no known size unless it is redirected from a file, in which
+	// case it can even be memory mapped
+	file := os.Stdin
+	if path != "-" {
+		var err error
+		file, err = os.Open(path)
 		if err != nil {
 			return nil, err
 		}
+		defer file.Close()
 	}
-
-	return result, nil
+	fileStats, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return processFile(file, fileStats.Size(), opts)
 }
 
-func processData(
-	wg *sync.WaitGroup,
-	data []byte,
-	limiterCh chan struct{},
-	resultsCh chan map[string]*Measurements,
-	errCh chan error,
-) {
-	defer wg.Done()
-
-	var result = make(map[string]*Measurements, 5000)
-	lastStationName := make([]byte, 30)
-	var lastStationLen int
-
-	var index int
-	for i := 0; i < len(data); i++ {
-		if data[i] == ';' {
-			stationName := data[index:i]
-			lastStationLen = copy(lastStationName, stationName)
-			index = i + 1
-			continue
-		} else if data[i] == '\n' {
-			temperatureBytes := data[index:i]
-			temperatureFloat := convertBytesToFloat(temperatureBytes)
-
-			stationNameUnsafe := unsafe.String(&lastStationName[0], lastStationLen)
-			existingStation, ok := result[stationNameUnsafe]
-			if !ok {
-				name := string(lastStationName[:lastStationLen])
-				result[name] = &Measurements{
-					Min:   temperatureFloat,
-					Max:   temperatureFloat,
-					Sum:   temperatureFloat,
-					Count: 1.0,
-				}
-			} else {
-				existingStation.Count += 1.0
-				existingStation.Sum += temperatureFloat
-				if temperatureFloat < existingStation.Min {
-					existingStation.Min = temperatureFloat
-				}
-				if temperatureFloat > existingStation.Max {
-					existingStation.Max = temperatureFloat
-				}
-			}
-			index = i + 1
-			continue
+// perFilePaths creates dir and returns the path in dir of the results of
+// every input, named after the input.
+func perFilePaths(dir string, inputs []string) (map[string]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	outputs := make(map[string]string, len(inputs))
+	inputOf := make(map[string]string, len(inputs))
+	for _, input := range inputs {
+		output := filepath.Join(dir, filepath.Base(input)+".out")
+		if other, ok := inputOf[output]; ok && other != input {
+			return nil, fmt.Errorf("%s and %s would both write their results to %s", other, input, output)
 		}
+		inputOf[output] = input
+		outputs[input] = output
 	}
-
-	resultsCh <- result
-	<-limiterCh
+	return outputs, nil
 }
 
-func aggregateMaps(resultsCh chan map[string]*Measurements) map[string]*Measurements {
-	finalMap := make(map[string]*Measurements, 200)
-	for result := range resultsCh {
-		for station, newMeasurement := range result {
-			existentMeasurement, ok := finalMap[station]
-			if !ok {
-				finalMap[station] = newMeasurement
-				continue
-			}
-			existentMeasurement.Count += newMeasurement.Count
-			existentMeasurement.Sum += newMeasurement.Sum
-			if newMeasurement.Min < existentMeasurement.Min {
-				existentMeasurement.Min = newMeasurement.Min
-			}
-			if newMeasurement.Max > existentMeasurement.Max {
-				existentMeasurement.Max = newMeasurement.Max
-			}
-		}
+// writeOutput writes the results to path, or to stdout if path is "-".
+func writeOutput(path string, writeResults output.Format, results map[string]*aggregate.Measurements, rounding round.Mode) error {
+	if path == "-" {
+		return writeResults(os.Stdout, results, rounding)
 	}
-
-	return finalMap
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := writeResults(file, results, rounding); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
 }
 
-func displayResults(results map[string]*Measurements) {
-	cities := make([]string, 0, len(results))
-	for city := range results {
-		cities = append(cities, city)
-	}
-	sort.Strings(cities)
-
-	for _, city := range cities {
-		measurement := results[city]
-		mean := measurement.Sum / measurement.Count
-		fmt.Println(
-			city,
-			"=",
-			roundFloat(measurement.Min, 1),
-			"/",
-			roundFloat(mean, 1),
-			"/",
-			roundFloat(measurement.Max, 1),
-		)
+func displaySkipped(w io.Writer, skipped *aggregate.Skipped) {
+	fmt.Fprintf(w, "Skipped %d malformed lines\n", skipped.Total())
+	for _, err := range aggregate.ParseErrors {
+		if count := skipped.Counts[err]; count > 0 {
+			fmt.Fprintf(w, "  %d: %v\n", count, err)
+		}
+	}
+	for _, sample := range skipped.Samples {
+		location := fmt.Sprintf("line %d (byte offset %d)", sample.Line, sample.Offset)
+		if sample.Path != "" {
+			location = sample.Path + ": " + location
+		}
+		fmt.Fprintf(w, "  %s: %q: %v\n", location, sample.Text, sample.Err)
 	}
 }
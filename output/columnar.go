@@ -0,0 +1,86 @@
+package output
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+
+	"1brc/aggregate"
+	"1brc/round"
+)
+
+// ColumnarMagic starts every file written by Columnar.
+const ColumnarMagic = "BRCC"
+
+// ColumnarVersion is the version of the format written by Columnar.
+const ColumnarVersion = 1
+
+// Types of the columns written by Columnar.
+const (
+	ColumnString  = 1
+	ColumnFloat64 = 2
+	ColumnInt64   = 3
+)
+
+// Columnar writes the results in a self-describing columnar binary format.
+// All integers are little endian.
+//
+//	magic    "BRCC"
+//	version  uint8
+//	columns  uint32, number of columns
+//	rows     uint64, number of rows
+//	for every column:
+//	  name   uint16 length followed by the UTF-8 bytes
+//	  type   uint8, ColumnString, ColumnFloat64 or ColumnInt64
+//	for every column, the values of all the rows:
+//	  string   uint32 length followed by the UTF-8 bytes
+//	  float64  IEEE 754 bits as uint64
+//	  int64    two's complement as uint64
+func Columnar(w io.Writer, results map[string]*aggregate.Measurements, rounding round.Mode) error {
+	rows := rows(results, rounding)
+	writer := bufio.NewWriter(w)
+	buf := make([]byte, 8)
+
+	putUint := func(v uint64, size int) {
+		binary.LittleEndian.PutUint64(buf, v)
+		writer.Write(buf[:size])
+	}
+	putString := func(s string, lengthSize int) {
+		putUint(uint64(len(s)), lengthSize)
+		writer.WriteString(s)
+	}
+
+	columns := []struct {
+		name       string
+		columnType uint8
+		value      func(r *row) uint64
+	}{
+		{"station", ColumnString, nil},
+		{"min", ColumnFloat64, func(r *row) uint64 { return math.Float64bits(r.Min) }},
+		{"mean", ColumnFloat64, func(r *row) uint64 { return math.Float64bits(r.Mean) }},
+		{"max", ColumnFloat64, func(r *row) uint64 { return math.Float64bits(r.Max) }},
+		{"count", ColumnInt64, func(r *row) uint64 { return uint64(r.Count) }},
+		{"sum", ColumnFloat64, func(r *row) uint64 { return math.Float64bits(r.Sum) }},
+	}
+
+	writer.WriteString(ColumnarMagic)
+	writer.WriteByte(ColumnarVersion)
+	putUint(uint64(len(columns)), 4)
+	putUint(uint64(len(rows)), 8)
+	for _, column := range columns {
+		putString(column.name, 2)
+		writer.WriteByte(column.columnType)
+	}
+	for _, column := range columns {
+		for i := range rows {
+			if column.columnType == ColumnString {
+				putString(rows[i].Station, 4)
+				continue
+			}
+			putUint(column.value(&rows[i]), 8)
+		}
+	}
+
+	return writer.Flush()
+}
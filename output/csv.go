@@ -0,0 +1,28 @@
+package output
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"1brc/aggregate"
+	"1brc/round"
+)
+
+// CSV writes the results as comma separated values with a header line.
+func CSV(w io.Writer, results map[string]*aggregate.Measurements, rounding round.Mode) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"station", "min", "mean", "max", "count", "sum"})
+	for _, r := range rows(results, rounding) {
+		writer.Write([]string{
+			r.Station,
+			strconv.FormatFloat(r.Min, 'f', 1, 64),
+			strconv.FormatFloat(r.Mean, 'f', 1, 64),
+			strconv.FormatFloat(r.Max, 'f', 1, 64),
+			strconv.FormatInt(r.Count, 10),
+			strconv.FormatFloat(r.Sum, 'f', 1, 64),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,17 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+
+	"1brc/aggregate"
+	"1brc/round"
+)
+
+// JSON writes the results as an array of objects with the station, min,
+// mean, max, count and sum fields.
+func JSON(w io.Writer, results map[string]*aggregate.Measurements, rounding round.Mode) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(rows(results, rounding))
+}
@@ -0,0 +1,80 @@
+package output
+
+import (
+	"bufio"
+	"io"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+
+	"1brc/aggregate"
+	"1brc/round"
+)
+
+// Official writes the results in the format of the reference implementation
+// of the challenge: {Abha=-23.0/18.0/59.2, Abidjan=-16.2/26.0/67.3, ...}
+// followed by a new line. Every temperature has exactly one decimal digit.
+// The stations are sorted like the Java TreeMap of the reference, by UTF-16
+// code units.
+func Official(w io.Writer, results map[string]*aggregate.Measurements, rounding round.Mode) error {
+	writer := bufio.NewWriter(w)
+	buf := make([]byte, 0, 128)
+	writer.WriteByte('{')
+	for i, station := range sortedStationsUTF16(results) {
+		measurement := results[station]
+		buf = buf[:0]
+		if i > 0 {
+			buf = append(buf, ", "...)
+		}
+		buf = append(buf, station...)
+		buf = append(buf, '=')
+		buf = strconv.AppendFloat(buf, rounding.Round(measurement.MinTemperature(), 1), 'f', 1, 64)
+		buf = append(buf, '/')
+		buf = strconv.AppendFloat(buf, rounding.Round(measurement.Mean(), 1), 'f', 1, 64)
+		buf = append(buf, '/')
+		buf = strconv.AppendFloat(buf, rounding.Round(measurement.MaxTemperature(), 1), 'f', 1, 64)
+		writer.Write(buf)
+	}
+	writer.WriteString("}\n")
+	return writer.Flush()
+}
+
+// sortedStationsUTF16 returns the stations in the order of their UTF-16
+// encodings, which differs from the byte order of UTF-8 when a character
+// between U+E000 and U+FFFF is compared with one above U+FFFF.
+func sortedStationsUTF16(results map[string]*aggregate.Measurements) []string {
+	stations := sortedStations(results)
+	sort.SliceStable(stations, func(i, j int) bool {
+		return lessUTF16(stations[i], stations[j])
+	})
+	return stations
+}
+
+// lessUTF16 reports whether a sorts before b when both are compared by
+// UTF-16 code units, like String.compareTo in Java.
+func lessUTF16(a, b string) bool {
+	for len(a) > 0 && len(b) > 0 {
+		ra, sizeA := utf8.DecodeRuneInString(a)
+		rb, sizeB := utf8.DecodeRuneInString(b)
+		if ra != rb {
+			unitA, unitB := firstUTF16Unit(ra), firstUTF16Unit(rb)
+			if unitA != unitB {
+				return unitA < unitB
+			}
+			// two supplementary characters with the same high surrogate
+			// compare like their low surrogates, in code point order
+			return ra < rb
+		}
+		a, b = a[sizeA:], b[sizeB:]
+	}
+	return len(a) == 0 && len(b) > 0
+}
+
+// firstUTF16Unit returns the first UTF-16 code unit of r, its high
+// surrogate if r is above U+FFFF.
+func firstUTF16Unit(r rune) rune {
+	if r > 0xFFFF {
+		return 0xD800 + (r-0x10000)>>10
+	}
+	return r
+}
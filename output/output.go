@@ -0,0 +1,80 @@
+// Package output writes the measurements of the stations in the supported
+// formats.
+package output
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"1brc/aggregate"
+	"1brc/round"
+)
+
+// Format writes the results sorted by station name to w, rounding the
+// temperatures to one decimal digit with rounding.
+type Format func(w io.Writer, results map[string]*aggregate.Measurements, rounding round.Mode) error
+
+// Formats maps the name of every supported format to its Format.
+var Formats = map[string]Format{
+	"text":     Text,
+	"official": Official,
+	"json":     JSON,
+	"csv":      CSV,
+	"columnar": Columnar,
+}
+
+// Names returns the names of the supported formats in alphabetical order.
+func Names() []string {
+	names := make([]string, 0, len(Formats))
+	for name := range Formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the Format called name.
+func Lookup(name string) (Format, error) {
+	format, ok := Formats[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q, expected one of %v", name, Names())
+	}
+	return format, nil
+}
+
+func sortedStations(results map[string]*aggregate.Measurements) []string {
+	stations := make([]string, 0, len(results))
+	for station := range results {
+		stations = append(stations, station)
+	}
+	sort.Strings(stations)
+	return stations
+}
+
+// row is the record written by the machine readable formats.
+type row struct {
+	Station string  `json:"station"`
+	Min     float64 `json:"min"`
+	Mean    float64 `json:"mean"`
+	Max     float64 `json:"max"`
+	Count   int64   `json:"count"`
+	Sum     float64 `json:"sum"`
+}
+
+func rows(results map[string]*aggregate.Measurements, rounding round.Mode) []row {
+	stations := sortedStations(results)
+	rows := make([]row, len(stations))
+	for i, station := range stations {
+		measurement := results[station]
+		rows[i] = row{
+			Station: station,
+			Min:     measurement.MinTemperature(),
+			Mean:    rounding.Round(measurement.Mean(), 1),
+			Max:     measurement.MaxTemperature(),
+			Count:   measurement.Count,
+			Sum:     float64(measurement.Sum) / 10,
+		}
+	}
+	return rows
+}
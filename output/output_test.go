@@ -0,0 +1,185 @@
+package output
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+
+	"1brc/aggregate"
+	"1brc/round"
+)
+
+var results = map[string]*aggregate.Measurements{
+	"Abha":   {Min: -230, Max: 592, Sum: 180, Count: 1},
+	"Accra":  {Min: -5, Max: 0, Sum: -1, Count: 2},
+	"Zürich": {Min: 93, Max: 93, Sum: 93, Count: 1},
+}
+
+func TestFormats(t *testing.T) {
+	tests := []struct {
+		format   Format
+		expected string
+	}{
+		{Text, "Abha = -23 / 18 / 59.2\nAccra = -0.5 / 0 / 0\nZürich = 9.3 / 9.3 / 9.3\n"},
+		{Official, "{Abha=-23.0/18.0/59.2, Accra=-0.5/0.0/0.0, Zürich=9.3/9.3/9.3}\n"},
+		{CSV, "station,min,mean,max,count,sum\nAbha,-23.0,18.0,59.2,1,18.0\nAccra,-0.5,0.0,0.0,2,-0.1\nZürich,9.3,9.3,9.3,1,9.3\n"},
+		{JSON, `[
+  {
+    "station": "Abha",
+    "min": -23,
+    "mean": 18,
+    "max": 59.2,
+    "count": 1,
+    "sum": 18
+  },
+  {
+    "station": "Accra",
+    "min": -0.5,
+    "mean": 0,
+    "max": 0,
+    "count": 2,
+    "sum": -0.1
+  },
+  {
+    "station": "Zürich",
+    "min": 9.3,
+    "mean": 9.3,
+    "max": 9.3,
+    "count": 1,
+    "sum": 9.3
+  }
+]
+`},
+	}
+
+	for _, test := range tests {
+		var out bytes.Buffer
+		if err := test.format(&out, results, round.HalfUp); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != test.expected {
+			t.Errorf("got\n%s\nexpected\n%s", out.String(), test.expected)
+		}
+	}
+}
+
+func TestOfficialUTF16Order(t *testing.T) {
+	results := map[string]*aggregate.Measurements{
+		"a\uff21": {Min: 10, Max: 10, Sum: 10, Count: 1},
+		"a😀":      {Min: 20, Max: 20, Sum: 20, Count: 1},
+		"a":       {Min: 30, Max: 30, Sum: 30, Count: 1},
+		"a\u00e9": {Min: 40, Max: 40, Sum: 40, Count: 1},
+	}
+	var out bytes.Buffer
+	if err := Official(&out, results, round.HalfUp); err != nil {
+		t.Fatal(err)
+	}
+	// U+1F600 is encoded as the surrogates D83D DE00, before U+FF21
+	expected := "{a=3.0/3.0/3.0, a\u00e9=4.0/4.0/4.0, a😀=2.0/2.0/2.0, a\uff21=1.0/1.0/1.0}\n"
+	if out.String() != expected {
+		t.Errorf("got %q, expected %q", out.String(), expected)
+	}
+}
+
+func TestColumnar(t *testing.T) {
+	var out bytes.Buffer
+	if err := Columnar(&out, results, round.HalfUp); err != nil {
+		t.Fatal(err)
+	}
+	header := []byte(ColumnarMagic + "\x01\x06\x00\x00\x00\x03\x00\x00\x00\x00\x00\x00\x00\x07\x00station\x01")
+	if !bytes.HasPrefix(out.Bytes(), header) {
+		t.Errorf("columnar output starts with %q", out.Bytes()[:len(header)])
+	}
+
+	decoded, err := decodeColumnar(out.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []row{
+		{Station: "Abha", Min: -23, Mean: 18, Max: 59.2, Count: 1, Sum: 18},
+		{Station: "Accra", Min: -0.5, Mean: 0, Max: 0, Count: 2, Sum: -0.1},
+		{Station: "Zürich", Min: 9.3, Mean: 9.3, Max: 9.3, Count: 1, Sum: 9.3},
+	}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("decoded %v, expected %v", decoded, expected)
+	}
+}
+
+// decodeColumnar reads the rows of a file written by Columnar, checking
+// every part of its layout.
+func decodeColumnar(data []byte) ([]row, error) {
+	reader := bytes.NewReader(data)
+	readUint := func(size int) uint64 {
+		buf := make([]byte, 8)
+		io.ReadFull(reader, buf[:size])
+		return binary.LittleEndian.Uint64(buf)
+	}
+	readString := func(lengthSize int) string {
+		buf := make([]byte, readUint(lengthSize))
+		io.ReadFull(reader, buf)
+		return string(buf)
+	}
+
+	magic := make([]byte, len(ColumnarMagic))
+	io.ReadFull(reader, magic)
+	if string(magic) != ColumnarMagic {
+		return nil, fmt.Errorf("magic %q", magic)
+	}
+	if version := readUint(1); version != ColumnarVersion {
+		return nil, fmt.Errorf("version %d", version)
+	}
+	columns := readUint(4)
+	rows := make([]row, readUint(8))
+	names := make([]string, columns)
+	types := make([]uint64, columns)
+	for i := range names {
+		names[i] = readString(2)
+		types[i] = readUint(1)
+	}
+	for i, name := range names {
+		for j := range rows {
+			r := &rows[j]
+			switch {
+			case name == "station" && types[i] == ColumnString:
+				r.Station = readString(4)
+			case name == "count" && types[i] == ColumnInt64:
+				r.Count = int64(readUint(8))
+			case types[i] == ColumnFloat64:
+				value := math.Float64frombits(readUint(8))
+				switch name {
+				case "min":
+					r.Min = value
+				case "mean":
+					r.Mean = value
+				case "max":
+					r.Max = value
+				case "sum":
+					r.Sum = value
+				default:
+					return nil, fmt.Errorf("unknown column %q", name)
+				}
+			default:
+				return nil, fmt.Errorf("column %q of type %d", name, types[i])
+			}
+		}
+	}
+	if reader.Len() != 0 {
+		return nil, fmt.Errorf("%d bytes after the last column", reader.Len())
+	}
+	return rows, nil
+}
+
+func TestLookup(t *testing.T) {
+	for _, name := range Names() {
+		if _, err := Lookup(name); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := Lookup("xml"); err == nil {
+		t.Error("Lookup(\"xml\") should fail")
+	}
+}
@@ -0,0 +1,30 @@
+package output
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"1brc/aggregate"
+	"1brc/round"
+)
+
+// Text writes one "station = min / mean / max" line per station.
+func Text(w io.Writer, results map[string]*aggregate.Measurements, rounding round.Mode) error {
+	writer := bufio.NewWriter(w)
+	for _, city := range sortedStations(results) {
+		measurement := results[city]
+		mean := measurement.Mean()
+		fmt.Fprintln(
+			writer,
+			city,
+			"=",
+			rounding.Round(measurement.MinTemperature(), 1),
+			"/",
+			rounding.Round(mean, 1),
+			"/",
+			rounding.Round(measurement.MaxTemperature(), 1),
+		)
+	}
+	return writer.Flush()
+}
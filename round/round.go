@@ -0,0 +1,69 @@
+// Package round rounds temperatures to a number of decimal digits with
+// selectable tie-breaking rules.
+package round
+
+import (
+	"fmt"
+	"math"
+)
+
+// Mode selects how a value exactly halfway between two results is rounded.
+type Mode int
+
+const (
+	// HalfUp rounds ties toward positive infinity, like Math.round in the
+	// Java reference implementation: -0.05 rounds to 0.0, 0.05 to 0.1.
+	HalfUp Mode = iota
+	// HalfEven rounds ties to the nearest even digit: 0.25 rounds to 0.2.
+	HalfEven
+	// HalfAwayFromZero rounds ties away from zero: -0.05 rounds to -0.1.
+	HalfAwayFromZero
+)
+
+var modeNames = map[Mode]string{
+	HalfUp:           "half-up",
+	HalfEven:         "half-even",
+	HalfAwayFromZero: "half-away",
+}
+
+// Names returns the names of the modes accepted by ParseMode.
+func Names() []string {
+	return []string{modeNames[HalfUp], modeNames[HalfEven], modeNames[HalfAwayFromZero]}
+}
+
+// ParseMode returns the Mode called name.
+func ParseMode(name string) (Mode, error) {
+	for mode, modeName := range modeNames {
+		if modeName == name {
+			return mode, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown rounding mode %q, expected one of %v", name, Names())
+}
+
+func (m Mode) String() string {
+	if name, ok := modeNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("Mode(%d)", int(m))
+}
+
+// Round rounds val to precision decimal digits.
+// The result is never negative zero, so that it is not printed as "-0".
+func (m Mode) Round(val float64, precision uint) float64 {
+	ratio := math.Pow(10, float64(precision))
+	scaled := val * ratio
+	switch m {
+	case HalfEven:
+		scaled = math.RoundToEven(scaled)
+	case HalfAwayFromZero:
+		scaled = math.Round(scaled)
+	default:
+		scaled = math.Floor(scaled + 0.5)
+	}
+	rounded := scaled / ratio
+	if rounded == 0 {
+		return 0
+	}
+	return rounded
+}
@@ -0,0 +1,61 @@
+package round
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		val                        float64
+		halfUp, halfEven, halfAway float64
+	}{
+		{0, 0, 0, 0},
+		{math.Copysign(0, -1), 0, 0, 0},
+		{0.05, 0.1, 0, 0.1},
+		{-0.05, 0, 0, -0.1},
+		{-0.04, 0, 0, 0},
+		{-0.06, -0.1, -0.1, -0.1},
+		{0.25, 0.3, 0.2, 0.3},
+		{-0.25, -0.2, -0.2, -0.3},
+		{0.35, 0.4, 0.4, 0.4},
+		{-1.45, -1.4, -1.4, -1.5},
+		{1.45, 1.5, 1.4, 1.5},
+		{12.34, 12.3, 12.3, 12.3},
+		{-12.36, -12.4, -12.4, -12.4},
+		{99.95, 100, 100, 100},
+		{-99.95, -99.9, -100, -100},
+		{18.0, 18, 18, 18},
+	}
+
+	for _, test := range tests {
+		for _, c := range []struct {
+			mode     Mode
+			expected float64
+		}{
+			{HalfUp, test.halfUp},
+			{HalfEven, test.halfEven},
+			{HalfAwayFromZero, test.halfAway},
+		} {
+			got := c.mode.Round(test.val, 1)
+			if got != c.expected || (got == 0 && math.Signbit(got)) {
+				t.Errorf("%v.Round(%v, 1) = %v, expected %v", c.mode, test.val, got, c.expected)
+			}
+		}
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for _, name := range Names() {
+		mode, err := ParseMode(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mode.String() != name {
+			t.Errorf("ParseMode(%q) = %v", name, mode)
+		}
+	}
+	if _, err := ParseMode("up"); err == nil {
+		t.Error("ParseMode(\"up\") should fail")
+	}
+}
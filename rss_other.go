@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+// peakRSS returns the maximum resident set size of the process in bytes.
+func peakRSS() (int64, bool) {
+	return 0, false
+}